@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Development mailer: emails go to the log, or to .eml files in dir when it's set
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	email := format(f.from, msg)
+
+	if f.dir == "" {
+		log.Printf("📧 Email to %s (not sent, MAILER=log):\n%s", msg.To, email)
+		return nil
+	}
+
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+
+	file := filepath.Join(f.dir, fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String()[:8]))
+	if err := os.WriteFile(file, email, 0644); err != nil {
+		return err
+	}
+
+	log.Printf("📧 Email to %s written to %s", msg.To, file)
+	return nil
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Email sent by the API (plain text)
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional emails (verification links, password resets)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer used by the API, set by Init
+var Default Mailer
+
+// Init selects the mailer from MAILER: log (default, writes the emails to the log or MAIL_DIR) or smtp
+func Init() error {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "GoSport <no-reply@gosport.local>"
+	}
+
+	switch driver := os.Getenv("MAILER"); driver {
+	case "", "log":
+		Default = NewFile(os.Getenv("MAIL_DIR"), from)
+
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			return fmt.Errorf("invalid SMTP_PORT %q", os.Getenv("SMTP_PORT"))
+		}
+		smtp, err := NewSMTP(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+		if err != nil {
+			return err
+		}
+		Default = smtp
+
+	default:
+		return fmt.Errorf("unknown MAILER %q (allowed: log, smtp)", driver)
+	}
+
+	return nil
+}
+
+// Formats a message as an RFC 5322 email
+func format(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@gosport>\r\n", uuid.New().String())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes()
+}
@@ -1,29 +1,57 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"github.com/alex6damian/GoSport/backend/mailer"
 	"github.com/alex6damian/GoSport/backend/middleware"
 	"github.com/alex6damian/GoSport/backend/routes"
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
 	"github.com/alex6damian/GoSport/pkg/config"
 	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/storage"
 )
 
 func main() {
 	// Initialize Database and run migrations
 	database.InitDB()
 
-	// Initialize MinIO client and bucket
-	if err := config.InitMinio(); err != nil {
-		log.Fatalf("⚠️  WARNING: Failed to initialize MinIO: %v", err)
+	// Initialize storage (MinIO bucket or local directory, see STORAGE_DRIVER)
+	if err := storage.Init(); err != nil {
+		log.Fatalf("⚠️  WARNING: Failed to initialize storage: %v", err)
 	}
 
+	// Initialize the mailer (log or SMTP, see MAILER)
+	if err := mailer.Init(); err != nil {
+		log.Fatalf("⚠️  WARNING: Failed to initialize mailer: %v", err)
+	}
+
+	// Load the access token signing keys (see JWT_KEYS_DIR)
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatalf("⚠️  WARNING: Failed to load JWT keys: %v", err)
+	}
+
+	// Abort resumable uploads that were never completed
+	go abortExpiredUploads()
+
+	// Remove the stored files of deleted videos (retries failed cleanups)
+	go processCleanupJobs()
+
+	// Push processing progress of the workers to the event streams
+	go services.ListenForJobProgress(context.Background(), os.Getenv("DATABASE_URL"))
+
+	// Delete long-ended login sessions and old login events
+	go purgeAuthHistory()
+
 	// Fiber setup
 	app := fiber.New(fiber.Config{
 		AppName:      "GoSport API v1",
@@ -57,6 +85,12 @@ func main() {
 		})
 	})
 
+	// Public keys verifying access tokens
+	app.Get("/.well-known/jwks.json", routes.GetJWKS)
+
+	// Presigned URLs of the local storage driver
+	app.Get("/storage/*", routes.ServeStoredObject)
+
 	// Setup routes
 	setupRoutes(app)
 
@@ -74,29 +108,75 @@ func setupRoutes(app *fiber.App) {
 	// API group
 	api := app.Group("/api/v1")
 
-	// Auth routes
-	auth := api.Group("/auth", middleware.AuthRateLimiter()) // /api/v1/auth
-	auth.Post("/register", routes.Register)
-	auth.Post("/login", routes.Login)
+	// Auth routes. The strict limiter only guards credentials and emails: as group middleware it would be
+	// mounted on the whole /auth prefix and throttle refresh, logout and verification too.
+	// One instance, so the routes share the attempts budget.
+	authLimiter := middleware.AuthRateLimiter()
+	auth := api.Group("/auth") // /api/v1/auth
+	auth.Post("/register", authLimiter, routes.Register)
+	auth.Post("/login", authLimiter, routes.Login)
+	auth.Post("/password/forgot", authLimiter, routes.ForgotPassword) // Emails a single-use reset link
+	auth.Post("/password/reset", authLimiter, routes.ResetPassword)
+	auth.Post("/refresh", routes.Refresh)                          // Rotates the refresh token
+	auth.Post("/logout", middleware.AuthMiddleware, routes.Logout) // {"all": true} ends every session
+	auth.Get("/verify", routes.VerifyEmail)                        // Link emailed at registration (?token=)
+	auth.Post("/verify", routes.VerifyEmail)
+	auth.Post("/verify/resend", middleware.AuthMiddleware, routes.ResendVerification) // Own per-user cooldown
 	log.Println("✅ Auth routes registered")
 
 	// User routes
 	users := api.Group("/users")                                     // /api/v1/users
 	users.Get("/me", middleware.AuthMiddleware, routes.GetMyProfile) // Middleware acts first as authentication gate
 	users.Put("/me", middleware.AuthMiddleware, routes.UpdateMyProfile)
+	users.Put("/me/password", middleware.AuthMiddleware, routes.ChangeMyPassword)
+	users.Get("/me/sessions", middleware.AuthMiddleware, routes.GetMySessions) // Open sessions + login history
+	users.Delete("/me/sessions/:id", middleware.AuthMiddleware, routes.RevokeMySession)
+	users.Get("/me/likes", middleware.AuthMiddleware, routes.GetLikedVideos)
+	users.Get("/me/subscriptions", middleware.AuthMiddleware, routes.GetMySubscriptions) // Creators I follow
+	users.Get("/me/subscribers", middleware.AuthMiddleware, routes.GetMySubscribers)     // Users following me
 	users.Get("/:username", routes.GetUserProfileByUsername)
-	users.Get("/:username/videos", routes.GetUserVideos)
+	users.Get("/:username/videos", middleware.OptionalAuth, routes.GetUserVideos) // Owner and admins also see non-public videos
+	users.Post("/:username/subscribe", middleware.AuthMiddleware, routes.Subscribe)
+	users.Delete("/:username/subscribe", middleware.AuthMiddleware, routes.Unsubscribe)
 	log.Println("✅ User routes registered")
 
 	// Video routes
 	videos := api.Group("/videos")
-	videos.Post("/upload", middleware.AuthMiddleware, routes.UploadVideo)
-	videos.Get("/", routes.ListVideos)
-	videos.Get("/:id", routes.GetVideo)
+	videos.Post("/upload", middleware.AuthMiddleware, middleware.VerifiedOnly, routes.UploadVideo)
+	videos.Post("/uploads", middleware.AuthMiddleware, middleware.VerifiedOnly, routes.InitUpload) // Resumable uploads (registered before /:id)
+	videos.Post("/uploads/presigned", middleware.AuthMiddleware, middleware.VerifiedOnly, routes.InitPresignedUpload)
+	videos.Get("/uploads/:uploadId", middleware.AuthMiddleware, routes.GetUploadStatus)
+	videos.Put("/uploads/:uploadId/chunks/:index", middleware.AuthMiddleware, routes.UploadChunk)
+	videos.Post("/uploads/:uploadId/complete", middleware.AuthMiddleware, routes.CompleteUpload)
+	videos.Delete("/uploads/:uploadId", middleware.AuthMiddleware, routes.AbortUpload)
+	videos.Get("/", middleware.OptionalAuth, routes.ListVideos)
+	videos.Get("/:id", middleware.OptionalAuth, routes.GetVideo)
+	videos.Get("/:id/previews.vtt", middleware.OptionalAuth, routes.GetVideoPreviews)
+	videos.Get("/:id/hls/master.m3u8", middleware.OptionalAuth, routes.GetPlaybackMaster)
+	videos.Get("/:id/hls/:rendition/index.m3u8", middleware.OptionalAuth, routes.GetPlaybackVariant)
+	videos.Get("/:id/stream/original", middleware.OptionalAuth, routes.StreamOriginal) // Byte-range proxy (MEDIA_PROXY=true)
+	videos.Get("/:id/stream/hls/*", middleware.OptionalAuth, routes.StreamHLS)
+	videos.Get("/:id/processing", middleware.AuthMiddleware, routes.GetVideoProcessing)
+	videos.Get("/:id/processing/events", middleware.QueryTokenAuth, routes.StreamVideoProcessing) // SSE (EventSource can't set headers)
+	videos.Get("/:id/comments", middleware.OptionalAuth, routes.ListComments)                     // ?cursor=&limit=
+	videos.Post("/:id/comments", middleware.AuthMiddleware, middleware.VerifiedOnly, routes.CreateComment)
+	videos.Get("/:id/comments/:commentId/replies", middleware.OptionalAuth, routes.ListReplies)
+	videos.Put("/:id/comments/:commentId", middleware.AuthMiddleware, routes.UpdateComment)
+	videos.Delete("/:id/comments/:commentId", middleware.AuthMiddleware, routes.DeleteComment) // Author or admin
+	videos.Put("/:id/comments/:commentId/pin", middleware.AuthMiddleware, routes.PinComment)   // Video creator only
+	videos.Delete("/:id/comments/:commentId/pin", middleware.AuthMiddleware, routes.UnpinComment)
+	videos.Get("/:id/reaction", middleware.AuthMiddleware, routes.GetMyReaction)
+	videos.Put("/:id/reaction", middleware.AuthMiddleware, routes.SetReaction) // {"value": "like"} or {"value": "dislike"}
+	videos.Delete("/:id/reaction", middleware.AuthMiddleware, routes.ClearReaction)
 	videos.Put("/:id", middleware.AuthMiddleware, routes.UpdateVideo)
 	videos.Delete("/:id", middleware.AuthMiddleware, routes.DeleteVideo)
 	log.Println("✅ Video routes registered")
 
+	// Feed routes
+	feed := api.Group("/feed", middleware.AuthMiddleware)  // /api/v1/feed
+	feed.Get("/subscriptions", routes.GetSubscriptionFeed) // Latest videos of followed creators
+	log.Println("✅ Feed routes registered")
+
 	// News routes
 	news := api.Group("/news")
 	news.Get("/", routes.GetNews)                    // List all news
@@ -112,5 +192,45 @@ func setupRoutes(app *fiber.App) {
 	adminAuth.Delete("/feeds/:id", routes.DeleteRSSFeed)
 	adminAuth.Post("/feeds/:id/sync", routes.SyncRSSFeed)
 	adminAuth.Post("/feeds/sync-all", routes.SyncAllFeeds)
+	adminAuth.Get("/jobs", routes.GetProcessingJobs)
+	adminAuth.Get("/jobs/:id", routes.GetProcessingJob)
+	adminAuth.Post("/jobs/:id/retry", routes.RetryProcessingJob)
+	adminAuth.Get("/cleanup-jobs", routes.GetCleanupJobs)
+	adminAuth.Post("/cleanup-jobs/:id/retry", routes.RetryCleanupJob)
+	adminAuth.Get("/storage/orphans", routes.GetOrphanedObjects)
 	log.Println("✅ Admin routes registered")
 }
+
+// Periodically frees the MinIO parts of expired uploads
+func abortExpiredUploads() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		services.AbortExpiredUploads(database.DB)
+		<-ticker.C
+	}
+}
+
+// Periodically runs the due storage cleanup jobs
+func processCleanupJobs() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		services.ProcessCleanupJobs(database.DB)
+		<-ticker.C
+	}
+}
+
+// Periodically deletes expired and revoked login sessions, and old login events
+func purgeAuthHistory() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		services.PurgeExpiredSessions(database.DB)
+		services.PurgeLoginEvents(database.DB)
+		<-ticker.C
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAuthRateLimiterScope(t *testing.T) {
+	app := fiber.New()
+	setupRoutes(app)
+
+	post := func(path string) int {
+		req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader("{}"))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		return resp.StatusCode
+	}
+
+	// Invalid bodies are rejected before any database access, the limiter still counts them
+	for i := 1; i <= 5; i++ {
+		if status := post("/api/v1/auth/password/reset"); status == fiber.StatusTooManyRequests {
+			t.Fatalf("attempt %d was rate limited, want 5 attempts allowed", i)
+		}
+	}
+	if status := post("/api/v1/auth/password/reset"); status != fiber.StatusTooManyRequests {
+		t.Fatalf("6th reset attempt = %d, want %d", status, fiber.StatusTooManyRequests)
+	}
+
+	// Session and verification routes don't share that budget
+	for _, path := range []string{"/api/v1/auth/refresh", "/api/v1/auth/verify"} {
+		for i := 0; i < 10; i++ {
+			if status := post(path); status == fiber.StatusTooManyRequests {
+				t.Fatalf("POST %s was rate limited by the auth limiter", path)
+			}
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"log"
 	"strings"
 
+	"github.com/alex6damian/GoSport/backend/services"
 	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -30,10 +33,7 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		})
 	}
 
-	token := parts[1]
-	log.Printf("   Token: %s...\n", token[:20])
-
-	claims, err := utils.ValidateToken(token)
+	claims, err := authenticate(parts[1])
 	if err != nil {
 		log.Printf("   ❌ Token validation failed: %v\n", err)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -47,11 +47,80 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	c.Locals("userID", claims.UserID)
 	c.Locals("userEmail", claims.Email)
 	c.Locals("userRole", claims.Role)
+	c.Locals("sessionID", claims.SessionID)
 
 	log.Printf("   ✅ Context set\n")
 	return c.Next()
 }
 
+// QueryTokenAuth is AuthMiddleware for clients that can't set headers (EventSource),
+// the token may also be passed as ?token=...
+func QueryTokenAuth(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" {
+		if token := c.Query("token"); token != "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return AuthMiddleware(c)
+}
+
+// OptionalAuth sets the user context when a valid token is sent (header or ?token=),
+// anonymous requests go through without it. So do requests with an expired or invalid token:
+// the routes behind it serve public resources, and players keep a stale ?token= in their URLs.
+func OptionalAuth(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if parts := strings.Split(c.Get("Authorization"), " "); len(parts) == 2 && parts[0] == "Bearer" {
+		token = parts[1]
+	}
+	if token == "" {
+		return c.Next()
+	}
+
+	claims, err := authenticate(token)
+	if err != nil {
+		return c.Next()
+	}
+
+	c.Locals("userID", claims.UserID)
+	c.Locals("userEmail", claims.Email)
+	c.Locals("userRole", claims.Role)
+	c.Locals("sessionID", claims.SessionID)
+
+	return c.Next()
+}
+
+// Validates an access token and checks that its session wasn't revoked (logout, password or role change)
+func authenticate(token string) (*utils.Claims, error) {
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := services.CheckSession(database.DB, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// VerifiedOnly rejects users who haven't verified their email address, when REQUIRE_VERIFIED_EMAIL=true
+// (runs after AuthMiddleware)
+func VerifiedOnly(c *fiber.Ctx) error {
+	if !services.VerifiedEmailRequired() {
+		return c.Next()
+	}
+
+	var user models.User
+	if err := database.DB.Select("verified").First(&user, c.Locals("userID")).Error; err != nil || !user.Verified {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error":   "Please verify your email address first",
+		})
+	}
+
+	return c.Next()
+}
+
 // AdminOnly checks if user has admin role
 func AdminOnly(c *fiber.Ctx) error {
 	log.Printf("🔍 AdminOnly START - Method: %s, Path: %s\n", c.Method(), c.Path())
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	jwt "github.com/golang-jwt/jwt/v4"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+)
+
+func TestOptionalAuthStaleTokenIsAnonymous(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "secret")
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{
+		UserID:    1,
+		Role:      "user",
+		SessionID: "session",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-16 * time.Minute)),
+			Issuer:    "gosport-api",
+		},
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stands in for the playback of a public video: served to anyone
+	app := fiber.New()
+	app.Get("/videos/:id/hls/master.m3u8", OptionalAuth, func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("userID").(uint); ok {
+			return c.SendString("#EXTM3U for user")
+		}
+		return c.SendString("#EXTM3U")
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+	}{
+		{"no token", "/videos/1/hls/master.m3u8", ""},
+		{"expired ?token=", "/videos/1/hls/master.m3u8?token=" + expired, ""},
+		{"expired bearer token", "/videos/1/hls/master.m3u8", "Bearer " + expired},
+		{"malformed ?token=", "/videos/1/hls/master.m3u8?token=not-a-jwt", ""},
+		{"token signed with another secret", "/videos/1/hls/master.m3u8", "Bearer " + signedWith(t, "other")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.header)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != fiber.StatusOK || string(body) != "#EXTM3U" {
+				t.Errorf("got %d %q, want 200 played anonymously", resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func signedWith(t *testing.T, secret string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    "gosport-api",
+		},
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
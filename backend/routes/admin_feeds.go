@@ -5,7 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
-	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/services/rss"
 	"github.com/alex6damian/GoSport/backend/utils"
 	"github.com/alex6damian/GoSport/pkg/database"
 	"github.com/alex6damian/GoSport/pkg/models"
@@ -86,7 +86,7 @@ func GetRSSFeeds(c *fiber.Ctx) error {
 func SyncRSSFeed(c *fiber.Ctx) error {
 	feedID := c.Params("id")
 
-	rssService := services.NewRSSService(database.DB)
+	rssService := rss.NewRSSService(database.DB)
 
 	var id uint
 	if _, err := fmt.Sscanf(feedID, "%d", &id); err != nil {
@@ -113,7 +113,7 @@ func SyncRSSFeed(c *fiber.Ctx) error {
 
 // SyncAllFeeds triggers sync for all active feeds
 func SyncAllFeeds(c *fiber.Ctx) error {
-	rssService := services.NewRSSService(database.DB)
+	rssService := rss.NewRSSService(database.DB)
 
 	if err := rssService.SyncAllFeeds(); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
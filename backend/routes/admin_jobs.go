@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Job statuses an admin can filter on
+var jobStatuses = map[string]bool{
+	"queued":     true,
+	"processing": true,
+	"completed":  true,
+	"failed":     true,
+	"dead":       true,
+}
+
+// GetProcessingJobs lists video processing jobs, optionally filtered by status (e.g., ?status=dead)
+func GetProcessingJobs(c *fiber.Ctx) error {
+	pagination := utils.ParsePagination(c)
+	status := c.Query("status")
+
+	query := database.DB.Model(&models.ProcessingJob{})
+	if status != "" {
+		if !jobStatuses[status] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid status. Allowed: queued, processing, completed, failed, dead",
+			})
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var jobs []models.ProcessingJob
+	if err := query.
+		Order("updated_at DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Find(&jobs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch jobs",
+		})
+	}
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"jobs": jobs,
+	}, paginationMeta)
+}
+
+// GetProcessingJob returns a single job with its full logs
+func GetProcessingJob(c *fiber.Ctx) error {
+	jobID := c.Params("id")
+
+	var job models.ProcessingJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"job": job,
+		},
+	})
+}
+
+// RetryProcessingJob puts a dead or failed job back in the queue with a fresh attempt budget
+func RetryProcessingJob(c *fiber.Ctx) error {
+	jobID := c.Params("id")
+
+	var job models.ProcessingJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+
+	if job.Status != "dead" && job.Status != "failed" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Only dead or failed jobs can be retried (current status: %s)", job.Status),
+		})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&job).Updates(map[string]interface{}{
+			"status":          "queued",
+			"attempts":        0,
+			"next_attempt_at": nil,
+			"logs":            job.Logs + "requeued by admin\n",
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Video{}).Where("id = ?", job.VideoID).Update("status", "pending").Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to requeue job",
+		})
+	}
+
+	// Wake up the video worker (it falls back to polling if this is missed)
+	if err := database.NotifyJobQueued(database.DB, job.ID); err != nil {
+		log.Printf("Failed to notify workers about job ID %d: %v", job.ID, err)
+	}
+	if err := database.NotifyJobProgress(database.DB, job.VideoID); err != nil {
+		log.Printf("Failed to notify progress of video ID %d: %v", job.VideoID, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"message": "Job requeued successfully",
+			"job":     job,
+		},
+	})
+}
@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+	"github.com/alex6damian/GoSport/pkg/storage"
+)
+
+// Cleanup job statuses an admin can filter on
+var cleanupJobStatuses = map[string]bool{
+	"queued":    true,
+	"running":   true,
+	"completed": true,
+	"dead":      true,
+}
+
+// Max orphaned objects listed in a report page (totals cover the scanned part of the bucket)
+const maxOrphanReportEntries = 1000
+
+// Object stored in the bucket without a video owning it
+type orphanedObject struct {
+	Key           string    `json:"key"`
+	Size          int64     `json:"size"`
+	LastModified  time.Time `json:"last_modified"`
+	VideoID       uint      `json:"video_id,omitempty"`       // for worker output folders (videos/hls/id/, videos/thumbnails/id/)
+	CleanupStatus string    `json:"cleanup_status,omitempty"` // status of the cleanup job covering the object, if any
+}
+
+// GetCleanupJobs lists storage cleanup jobs of deleted videos, optionally filtered by status (e.g., ?status=dead)
+func GetCleanupJobs(c *fiber.Ctx) error {
+	pagination := utils.ParsePagination(c)
+	status := c.Query("status")
+
+	query := database.DB.Model(&models.CleanupJob{})
+	if status != "" {
+		if !cleanupJobStatuses[status] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid status. Allowed: queued, running, completed, dead",
+			})
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var jobs []models.CleanupJob
+	if err := query.
+		Order("updated_at DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Find(&jobs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to fetch cleanup jobs",
+		})
+	}
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"jobs": jobs,
+	}, paginationMeta)
+}
+
+// RetryCleanupJob puts a dead cleanup job back in the queue with a fresh attempt budget
+func RetryCleanupJob(c *fiber.Ctx) error {
+	jobID := c.Params("id")
+
+	var job models.CleanupJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "Cleanup job not found",
+		})
+	}
+
+	if job.Status != "dead" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error":   fmt.Sprintf("Only dead cleanup jobs can be retried (current status: %s)", job.Status),
+		})
+	}
+
+	if err := database.DB.Model(&job).Updates(map[string]interface{}{
+		"status":          "queued",
+		"attempts":        0,
+		"next_attempt_at": nil,
+		"logs":            job.Logs + fmt.Sprintf("[%s] requeued by admin\n", time.Now().UTC().Format(time.RFC3339)),
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to requeue cleanup job",
+		})
+	}
+
+	go services.ProcessCleanupJobs(database.DB)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"message": "Cleanup job requeued successfully",
+			"job":     job,
+		},
+	})
+}
+
+// GetOrphanedObjects reports the objects in the bucket that no video row owns, a page at a time
+// (e.g., ?limit=100, then ?after=<next_after> for the next page)
+func GetOrphanedObjects(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", maxOrphanReportEntries)
+	if limit < 1 || limit > maxOrphanReportEntries {
+		limit = maxOrphanReportEntries
+	}
+
+	var videos []models.Video
+	if err := database.DB.Select("id", "minio_key").Find(&videos).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch videos", fiber.StatusInternalServerError)
+	}
+	videoIDs := make(map[uint]bool, len(videos))
+	videoKeys := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		videoIDs[video.ID] = true
+		videoKeys[video.MinioKey] = true
+	}
+
+	// Uploads in progress own their object until they are completed
+	var uploadKeys []string
+	database.DB.Model(&models.Upload{}).Where("status IN ?", []string{"active", "completing"}).Pluck("minio_key", &uploadKeys)
+	for _, key := range uploadKeys {
+		videoKeys[key] = true
+	}
+
+	// Objects already handed to a cleanup job
+	var cleanupJobs []models.CleanupJob
+	database.DB.Where("status <> ?", "completed").Find(&cleanupJobs)
+	cleanupByVideo := make(map[uint]string, len(cleanupJobs))
+	cleanupByKey := make(map[string]string)
+	for _, job := range cleanupJobs {
+		cleanupByVideo[job.VideoID] = job.Status
+		for _, object := range job.Objects {
+			cleanupByKey[object] = job.Status
+		}
+	}
+
+	// The listing is streamed, and stops once the page is full
+	orphans := make([]orphanedObject, 0)
+	var orphanBytes int64
+	var scanned int
+	var lastKey string
+	truncated := false
+	err := services.WalkObjects("", c.Query("after"), func(object storage.ObjectInfo) bool {
+		if len(orphans) == limit {
+			truncated = true
+			return false
+		}
+		scanned++
+		lastKey = object.Key
+
+		orphan := orphanedObject{Key: object.Key, Size: object.Size, LastModified: object.LastModified}
+		if videoID, ok := derivedAssetVideoID(object.Key); ok {
+			if videoIDs[videoID] {
+				return true
+			}
+			orphan.VideoID = videoID
+			orphan.CleanupStatus = cleanupByVideo[videoID]
+		} else {
+			if videoKeys[object.Key] {
+				return true
+			}
+			orphan.CleanupStatus = cleanupByKey[object.Key]
+		}
+
+		orphanBytes += object.Size
+		orphans = append(orphans, orphan)
+		return true
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fmt.Sprintf("Failed to list bucket: %v", err), fiber.StatusInternalServerError)
+	}
+
+	data := fiber.Map{
+		"orphans":         orphans,
+		"orphaned_count":  len(orphans),
+		"orphaned_bytes":  orphanBytes,
+		"scanned_objects": scanned,
+		"truncated":       truncated,
+	}
+	if truncated {
+		data["next_after"] = lastKey // ?after= of the next page
+	}
+
+	return utils.SuccessResponse(c, data)
+}
+
+// Video ID of a worker output object (videos/hls/<id>/... or videos/thumbnails/<id>/...)
+func derivedAssetVideoID(key string) (uint, bool) {
+	for _, prefix := range []string{"videos/hls/", "videos/thumbnails/"} {
+		rest, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+
+		id, _, _ := strings.Cut(rest, "/")
+		videoID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return uint(videoID), true
+	}
+	return 0, false
+}
@@ -1,6 +1,10 @@
 package routes
 
 import (
+	"errors"
+	"log"
+
+	"github.com/alex6damian/GoSport/backend/services"
 	"github.com/alex6damian/GoSport/backend/utils"
 	"github.com/alex6damian/GoSport/pkg/database"
 	"github.com/alex6damian/GoSport/pkg/models"
@@ -22,10 +26,36 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// AuthResponse represents the response containing the JWT token
+// RefreshRequest represents the expected payload for token refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest represents the optional payload for logout
+type LogoutRequest struct {
+	All bool `json:"all"` // end every session of the user, not only the current one
+}
+
+// VerifyEmailRequest represents the payload for email verification (the emailed link passes ?token= instead)
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPasswordRequest represents the expected payload to request a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents the expected payload to choose a new password with an emailed token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8,strong_password"`
+}
+
+// AuthResponse represents the response containing the JWT access token and the refresh token
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User UserResponse `json:"user"`
+	*services.SessionTokens
 }
 
 // UserResponse represents the user data returned in responses
@@ -35,6 +65,7 @@ type UserResponse struct {
 	Email     string `json:"email"`
 	Role      string `json:"role"`
 	Avatar    string `json:"avatar,omitempty"`
+	Verified  bool   `json:"verified"`
 	CreatedAt string `json:"created_at"`
 }
 
@@ -88,8 +119,15 @@ func Register(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "Failed to create user", fiber.StatusInternalServerError)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, user.Role)
+	// Email the verification link (the account works meanwhile, see REQUIRE_VERIFIED_EMAIL)
+	go func(user models.User) {
+		if err := services.SendVerificationEmail(database.DB, &user); err != nil {
+			log.Printf("Failed to send verification email to user ID %d: %v", user.ID, err)
+		}
+	}(user)
+
+	// Open a session (JWT access token + refresh token)
+	tokens, err := services.StartSession(database.DB, &user, c.IP(), c.Get(fiber.HeaderUserAgent))
 	if err != nil {
 		return utils.ErrorResponse(c, "Failed to generate token", fiber.StatusInternalServerError)
 	}
@@ -102,9 +140,10 @@ func Register(c *fiber.Ctx) error {
 			Email:     user.Email,
 			Role:      user.Role,
 			Avatar:    user.Avatar,
+			Verified:  user.Verified,
 			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		},
-		Token: token,
+		SessionTokens: tokens,
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -127,25 +166,39 @@ func Login(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, map[string]string{"error": err.Error()})
 	}
 
+	ip, userAgent := c.IP(), c.Get(fiber.HeaderUserAgent)
+
 	// Find user by email
 	var user models.User
 	if err := database.DB.Where("email=?", req.Email).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			services.CheckDummyPassword(req.Password)
+			services.RecordFailedLogin(database.DB, nil, req.Email, ip, userAgent, "unknown_email")
 			return utils.ErrorResponse(c, "Invalid credentials", fiber.StatusUnauthorized)
 		}
 		return utils.ErrorResponse(c, "Database error", fiber.StatusInternalServerError)
 	}
 
+	// Locked accounts are refused whatever the password, with the same answer as unknown emails
+	// and wrong passwords, so the lockout doesn't reveal that the account exists
+	if services.LockedUntil(&user) != nil {
+		services.CheckDummyPassword(req.Password)
+		services.RecordFailedLogin(database.DB, &user, req.Email, ip, userAgent, "locked")
+		return utils.ErrorResponse(c, "Invalid credentials", fiber.StatusUnauthorized)
+	}
+
 	// Check password
 	if !utils.CheckPassword(user.Password, req.Password) {
+		services.RecordFailedLogin(database.DB, &user, req.Email, ip, userAgent, "bad_password")
 		return utils.ErrorResponse(c, "Invalid credentials", fiber.StatusUnauthorized)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID, user.Email, user.Role)
+	// Open a session (JWT access token + refresh token)
+	tokens, err := services.StartSession(database.DB, &user, ip, userAgent)
 	if err != nil {
 		return utils.ErrorResponse(c, "Failed to generate token", fiber.StatusInternalServerError)
 	}
+	services.RecordLogin(database.DB, &user, tokens.SessionID, ip, userAgent)
 
 	// Response
 	response := AuthResponse{
@@ -155,10 +208,177 @@ func Login(c *fiber.Ctx) error {
 			Email:     user.Email,
 			Role:      user.Role,
 			Avatar:    user.Avatar,
+			Verified:  user.Verified,
 			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		},
-		Token: token,
+		SessionTokens: tokens,
 	}
 
 	return utils.SuccessResponse(c, response)
 }
+
+// Refresh handler - POST /api/v1/auth/refresh
+//
+// The refresh token is rotated: the response carries a new one, the old one stops working.
+func Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ValidationErrorResponse(c, map[string]string{"error": err.Error()})
+	}
+
+	tokens, err := services.RefreshSession(database.DB, req.RefreshToken, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		return utils.ErrorResponse(c, "Invalid or expired refresh token", fiber.StatusUnauthorized)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to refresh token", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, tokens)
+}
+
+// Logout handler - POST /api/v1/auth/logout
+//
+// Revokes the current session (or all of them with {"all": true}), its tokens stop working right away.
+func Logout(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID := c.Locals("sessionID").(string)
+
+	var req LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+		}
+	}
+
+	var err error
+	if req.All {
+		err = services.RevokeUserSessions(database.DB, userID, "logout_all")
+	} else {
+		err = services.RevokeSession(database.DB, sessionID, "logout")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to log out", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
+
+// VerifyEmail handler - GET /api/v1/auth/verify?token=... (emailed link) or POST /api/v1/auth/verify
+func VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" && c.Method() == fiber.MethodPost {
+		var req VerifyEmailRequest
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+		}
+		token = req.Token
+	}
+
+	if token == "" {
+		return utils.ErrorResponse(c, "Token is required", fiber.StatusBadRequest)
+	}
+
+	user, err := services.VerifyEmail(database.DB, token)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		return utils.ErrorResponse(c, "Invalid or expired verification link", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to verify email", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message":  "Email verified successfully",
+		"username": user.Username,
+	})
+}
+
+// ResendVerification handler - POST /api/v1/auth/verify/resend
+func ResendVerification(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return utils.ErrorResponse(c, "User not found", fiber.StatusNotFound)
+	}
+
+	if user.Verified {
+		return utils.ErrorResponse(c, "Email already verified", fiber.StatusConflict)
+	}
+
+	err := services.SendVerificationEmail(database.DB, &user)
+	if errors.Is(err, services.ErrEmailSentRecently) {
+		return utils.ErrorResponse(c, "A verification email was sent recently, try again in a minute", fiber.StatusTooManyRequests)
+	}
+	if err != nil {
+		log.Printf("Failed to send verification email to user ID %d: %v", user.ID, err)
+		return utils.ErrorResponse(c, "Failed to send verification email", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Verification email sent to " + user.Email,
+	})
+}
+
+// ForgotPassword handler - POST /api/v1/auth/password/forgot
+//
+// The response is the same whether the email belongs to an account or not.
+func ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ValidationErrorResponse(c, map[string]string{"error": err.Error()})
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		// Sent in the background, so the response time doesn't tell whether the account exists
+		go func(user models.User) {
+			if err := services.SendPasswordResetEmail(database.DB, &user); err != nil && !errors.Is(err, services.ErrEmailSentRecently) {
+				log.Printf("Failed to send password reset email to user ID %d: %v", user.ID, err)
+			}
+		}(user)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "If an account uses this email, a password reset link was sent to it",
+	})
+}
+
+// ResetPassword handler - POST /api/v1/auth/password/reset
+//
+// The token works once, every session of the user is revoked.
+func ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ValidationErrorResponse(c, map[string]string{"error": err.Error()})
+	}
+
+	err := services.ResetPassword(database.DB, req.Token, req.NewPassword)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		return utils.ErrorResponse(c, "Invalid or expired reset link", fiber.StatusBadRequest)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to reset password", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Password reset successfully, please log in with your new password",
+	})
+}
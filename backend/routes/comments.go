@@ -0,0 +1,360 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Comment content sent by clients (create and edit)
+type commentInput struct {
+	Content  string `json:"content" validate:"required,max=5000"`
+	ParentID *uint  `json:"parent_id"` // comment replied to (create only)
+}
+
+// Public author of a comment, without the email or account details
+type commentAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+}
+
+// Comment as sent to clients, its user replaced by the public author
+type commentResponse struct {
+	models.Comment
+	User commentAuthor `json:"user"`
+}
+
+// ListComments pages through the top-level comments of a video, newest first - GET /api/v1/videos/:id/comments
+//
+// The first page also carries the pinned comment, which is left out of the pages.
+func ListComments(c *fiber.Ctx) error {
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := utils.ParseCursor(c)
+	if err != nil {
+		return err
+	}
+
+	query := database.DB.Scopes(preloadCommentAuthor).
+		Where("video_id = ? AND parent_id IS NULL AND pinned = ?", video.ID, false)
+	if cursor.Cursor != 0 {
+		query = query.Where("id < ?", cursor.Cursor)
+	}
+
+	// One extra row tells whether another page follows
+	var comments []models.Comment
+	if err := query.Order("id DESC").Limit(cursor.Limit + 1).Find(&comments).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch comments", fiber.StatusInternalServerError)
+	}
+	comments, meta := cursorPage(comments, cursor.Limit)
+
+	data := fiber.Map{
+		"comments": commentResponses(comments),
+	}
+	if cursor.Cursor == 0 {
+		var pinned models.Comment
+		if err := database.DB.Scopes(preloadCommentAuthor).Where("video_id = ? AND pinned = ?", video.ID, true).First(&pinned).Error; err == nil {
+			data["pinned"] = newCommentResponse(&pinned)
+		}
+	}
+
+	return utils.CursorPaginatedResponse(c, data, meta)
+}
+
+// ListReplies pages through the replies to a comment, oldest first - GET /api/v1/videos/:id/comments/:commentId/replies
+func ListReplies(c *fiber.Ctx) error {
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	parent, err := findComment(c, video)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := utils.ParseCursor(c)
+	if err != nil {
+		return err
+	}
+
+	query := database.DB.Scopes(preloadCommentAuthor).Where("parent_id = ?", parent.ID)
+	if cursor.Cursor != 0 {
+		query = query.Where("id > ?", cursor.Cursor)
+	}
+
+	var replies []models.Comment
+	if err := query.Order("id ASC").Limit(cursor.Limit + 1).Find(&replies).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch replies", fiber.StatusInternalServerError)
+	}
+	replies, meta := cursorPage(replies, cursor.Limit)
+
+	return utils.CursorPaginatedResponse(c, fiber.Map{
+		"replies": commentResponses(replies),
+	}, meta)
+}
+
+// CreateComment comments on a video, or replies to a comment with parent_id - POST /api/v1/videos/:id/comments
+//
+// Replying to a reply adds to the same thread (replies are one level deep).
+func CreateComment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	var req commentInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	comment := models.Comment{
+		UserID:  userID,
+		VideoID: video.ID,
+		Content: req.Content,
+	}
+
+	if req.ParentID != nil {
+		var parent models.Comment
+		if err := database.DB.Where("id = ? AND video_id = ?", *req.ParentID, video.ID).First(&parent).Error; err != nil {
+			return utils.ErrorResponse(c, "Parent comment not found", fiber.StatusNotFound)
+		}
+
+		threadID := parent.ID
+		if parent.ParentID != nil {
+			threadID = *parent.ParentID
+		}
+		comment.ParentID = &threadID
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&models.Comment{}).Where("id = ?", *comment.ParentID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to create comment", fiber.StatusInternalServerError)
+	}
+
+	database.DB.Scopes(preloadCommentAuthor).First(&comment, comment.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    newCommentResponse(&comment),
+	})
+}
+
+// UpdateComment edits the content of your comment - PUT /api/v1/videos/:id/comments/:commentId
+func UpdateComment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	comment, err := findComment(c, video)
+	if err != nil {
+		return err
+	}
+
+	// Check ownership
+	if comment.UserID != userID {
+		return utils.ErrorResponse(c, "You don't have permission to edit this comment", fiber.StatusForbidden)
+	}
+
+	var req commentInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(comment).Updates(map[string]interface{}{
+		"content":   req.Content,
+		"edited_at": now,
+	}).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to update comment", fiber.StatusInternalServerError)
+	}
+	comment.Content = req.Content
+	comment.EditedAt = &now
+
+	return utils.SuccessResponse(c, newCommentResponse(comment))
+}
+
+// DeleteComment removes a comment with its replies - DELETE /api/v1/videos/:id/comments/:commentId
+//
+// Authors delete their own comments, admins remove any comment.
+func DeleteComment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	role, _ := c.Locals("userRole").(string)
+
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	comment, err := findComment(c, video)
+	if err != nil {
+		return err
+	}
+
+	// Check ownership
+	if comment.UserID != userID && role != "admin" {
+		return utils.ErrorResponse(c, "You don't have permission to delete this comment", fiber.StatusForbidden)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("parent_id = ?", comment.ID).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&models.Comment{}).Where("id = ?", *comment.ParentID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count - 1")).Error
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to delete comment", fiber.StatusInternalServerError)
+	}
+
+	if comment.UserID != userID {
+		log.Printf("🛡️ Admin user ID %d removed comment ID %d on video ID %d", userID, comment.ID, video.ID)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Comment deleted successfully",
+	})
+}
+
+// PinComment pins a top-level comment on your video, replacing the pinned one - PUT /api/v1/videos/:id/comments/:commentId/pin
+func PinComment(c *fiber.Ctx) error {
+	return setCommentPinned(c, true)
+}
+
+// UnpinComment unpins a comment of your video - DELETE /api/v1/videos/:id/comments/:commentId/pin
+func UnpinComment(c *fiber.Ctx) error {
+	return setCommentPinned(c, false)
+}
+
+func setCommentPinned(c *fiber.Ctx, pinned bool) error {
+	userID := c.Locals("userID").(uint)
+
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	// Only the creator of the video pins comments
+	if video.UserID != userID {
+		return utils.ErrorResponse(c, "Only the video creator can pin comments", fiber.StatusForbidden)
+	}
+
+	comment, err := findComment(c, video)
+	if err != nil {
+		return err
+	}
+
+	if comment.ParentID != nil {
+		return utils.ErrorResponse(c, "Replies can't be pinned", fiber.StatusBadRequest)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if pinned {
+			if err := tx.Model(&models.Comment{}).Where("video_id = ? AND pinned = ?", video.ID, true).
+				Update("pinned", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(comment).Update("pinned", pinned).Error
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to pin comment", fiber.StatusInternalServerError)
+	}
+	comment.Pinned = pinned
+
+	return utils.SuccessResponse(c, newCommentResponse(comment))
+}
+
+// Loads the :commentId comment of the video, with its author
+func findComment(c *fiber.Ctx, video *models.Video) (*models.Comment, error) {
+	var comment models.Comment
+	err := database.DB.Scopes(preloadCommentAuthor).Where("id = ? AND video_id = ?", c.Params("commentId"), video.ID).First(&comment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Comment not found")
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch comment")
+	}
+
+	return &comment, nil
+}
+
+// Loads the authors of the comments, only the columns of commentAuthor
+func preloadCommentAuthor(db *gorm.DB) *gorm.DB {
+	return db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "avatar")
+	})
+}
+
+func newCommentResponse(comment *models.Comment) commentResponse {
+	return commentResponse{
+		Comment: *comment,
+		User: commentAuthor{
+			ID:       comment.User.ID,
+			Username: comment.User.Username,
+			Avatar:   comment.User.Avatar,
+		},
+	}
+}
+
+func commentResponses(comments []models.Comment) []commentResponse {
+	responses := make([]commentResponse, len(comments))
+	for i := range comments {
+		responses[i] = newCommentResponse(&comments[i])
+	}
+	return responses
+}
+
+// Trims the extra row fetched past the limit, and builds the cursor to the next page
+func cursorPage(comments []models.Comment, limit int) ([]models.Comment, utils.CursorMeta) {
+	hasNext := len(comments) > limit
+	if hasNext {
+		comments = comments[:limit]
+	}
+
+	var lastID uint
+	if len(comments) > 0 {
+		lastID = comments[len(comments)-1].ID
+	}
+
+	return comments, utils.CreateCursorMeta(limit, lastID, hasNext)
+}
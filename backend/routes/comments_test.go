@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+func TestCursorPage(t *testing.T) {
+	// Rows as fetched: limit + 1 at most, newest first
+	rows := func(ids ...uint) []models.Comment {
+		comments := make([]models.Comment, len(ids))
+		for i, id := range ids {
+			comments[i].ID = id
+		}
+		return comments
+	}
+
+	tests := []struct {
+		name       string
+		fetched    []models.Comment
+		limit      int
+		wantLen    int
+		wantCursor string
+		wantNext   bool
+	}{
+		{"extra row: another page follows", rows(10, 9, 8, 7), 3, 3, "8", true},
+		{"exactly the limit: last page", rows(10, 9, 8), 3, 3, "", false},
+		{"short page", rows(10, 9), 3, 2, "", false},
+		{"empty", rows(), 3, 0, "", false},
+		{"single item pages", rows(5, 4), 1, 1, "5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, meta := cursorPage(tt.fetched, tt.limit)
+			if len(page) != tt.wantLen {
+				t.Errorf("got %d comments, want %d", len(page), tt.wantLen)
+			}
+			if meta.NextCursor != tt.wantCursor || meta.HasNext != tt.wantNext || meta.Limit != tt.limit {
+				t.Errorf("meta = %+v, want next cursor %q, has next %v", meta, tt.wantCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCommentResponseHidesAuthorEmail(t *testing.T) {
+	comment := models.Comment{
+		ID:      1,
+		UserID:  7,
+		Content: "Great goal!",
+		User: models.User{
+			ID:       7,
+			Username: "fan",
+			Email:    "fan@example.com",
+			Role:     "admin",
+			Avatar:   "avatars/7.png",
+		},
+	}
+
+	data, err := json.Marshal(fiber.Map{
+		"comments": commentResponses([]models.Comment{comment}),
+		"pinned":   newCommentResponse(&comment),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := string(data)
+	for _, leaked := range []string{"email", "fan@example.com", "role", "verified"} {
+		if strings.Contains(body, leaked) {
+			t.Errorf("response contains %q: %s", leaked, body)
+		}
+	}
+
+	var decoded struct {
+		Pinned struct {
+			User map[string]any `json:"user"`
+		} `json:"pinned"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"id": float64(7), "username": "fan", "avatar": "avatars/7.png"}
+	if !reflect.DeepEqual(decoded.Pinned.User, want) {
+		t.Errorf("author = %v, want %v", decoded.Pinned.User, want)
+	}
+}
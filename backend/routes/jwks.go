@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+)
+
+// GetJWKS publishes the public keys verifying access tokens - GET /.well-known/jwks.json
+//
+// Other services verify tokens with these keys (matched by the kid header), without sharing a secret.
+func GetJWKS(c *fiber.Ctx) error {
+	// Short cache, so rotated keys show up quickly
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(utils.JWKS())
+}
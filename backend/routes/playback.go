@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Validity of the presigned segment URLs (VOD playlists are fetched once, so it must cover a whole viewing)
+const playbackURLExpiry = 4 * time.Hour
+
+const hlsContentType = "application/vnd.apple.mpegurl"
+
+// Playback URL of a video (master playlist served by the API)
+func playbackURL(video *models.Video) string {
+	return fmt.Sprintf("/api/v1/videos/%d/hls/master.m3u8", video.ID)
+}
+
+// GetPlaybackMaster serves the HLS master playlist of a ready video - GET /api/v1/videos/:id/hls/master.m3u8
+//
+// Variant URIs are relative (e.g., 720p/index.m3u8), so they resolve to GetPlaybackVariant.
+func GetPlaybackMaster(c *fiber.Ctx) error {
+	video, err := findPlayableVideo(c)
+	if err != nil {
+		return err
+	}
+
+	content, err := services.ReadObject(video.HLSPath)
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to load playlist", fiber.StatusInternalServerError)
+	}
+
+	c.Set(fiber.HeaderContentType, hlsContentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	return c.Send(content)
+}
+
+// GetPlaybackVariant serves a rendition playlist with every segment URI presigned - GET /api/v1/videos/:id/hls/:rendition/index.m3u8
+func GetPlaybackVariant(c *fiber.Ctx) error {
+	video, err := findPlayableVideo(c)
+	if err != nil {
+		return err
+	}
+
+	// Renditions are direct children of the HLS folder (videos/hls/<id>/720p/index.m3u8)
+	rendition := c.Params("rendition")
+	if rendition == "" || rendition == "." || strings.Contains(rendition, "..") || strings.ContainsAny(rendition, `/\`) {
+		return utils.ErrorResponse(c, "Rendition not found", fiber.StatusNotFound)
+	}
+	renditionDir := path.Join(path.Dir(video.HLSPath), rendition)
+
+	content, err := services.ReadObject(path.Join(renditionDir, "index.m3u8"))
+	if err != nil {
+		return utils.ErrorResponse(c, "Rendition not found", fiber.StatusNotFound)
+	}
+
+	// Every line that isn't a tag or blank is a segment URI, relative to the playlist
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		uri := strings.TrimSpace(line)
+		if uri == "" || strings.HasPrefix(uri, "#") {
+			continue
+		}
+
+		// Clients that can't reach MinIO get the segments through the streaming proxy
+		if mediaProxyEnabled() {
+			lines[i] = fmt.Sprintf("/api/v1/videos/%d/stream/hls/%s/%s", video.ID, rendition, uri)
+			continue
+		}
+
+		url, err := services.GetVideoURL(path.Join(renditionDir, uri), playbackURLExpiry)
+		if err != nil {
+			return utils.ErrorResponse(c, "Failed to generate segment URLs", fiber.StatusInternalServerError)
+		}
+		lines[i] = url
+	}
+
+	c.Set(fiber.HeaderContentType, hlsContentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+	return c.SendString(strings.Join(lines, "\n"))
+}
+
+// Loads the :id video, it must be processed (errors are *fiber.Error, rendered by the error handler)
+func findPlayableVideo(c *fiber.Ctx) (*models.Video, error) {
+	var video models.Video
+	if err := database.DB.First(&video, c.Params("id")).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Video not found")
+	}
+
+	if video.Status != "ready" || video.HLSPath == "" {
+		return nil, fiber.NewError(fiber.StatusConflict, "Video is not ready for playback")
+	}
+
+	if !canWatchVideo(c, &video) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Video not found")
+	}
+
+	return &video, nil
+}
+
+// Loads the :id video, the user must be able to watch it (errors are *fiber.Error, rendered by the error handler)
+func findWatchableVideo(c *fiber.Ctx, db *gorm.DB) (*models.Video, error) {
+	var video models.Video
+	if err := db.First(&video, c.Params("id")).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Video not found")
+	}
+
+	if !canWatchVideo(c, &video) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Video not found")
+	}
+
+	return &video, nil
+}
+
+// Whether the requesting user (if any, see middleware.OptionalAuth) may watch the video:
+// published public or unlisted videos are open to anyone, the others only to their owner and admins
+func canWatchVideo(c *fiber.Ctx, video *models.Video) bool {
+	userID, _ := c.Locals("userID").(uint)
+	role, _ := c.Locals("userRole").(string)
+
+	if (userID != 0 && userID == video.UserID) || role == "admin" {
+		return true
+	}
+
+	published := video.PublishAt == nil || !video.PublishAt.After(time.Now())
+	return video.Status == "ready" && video.Visibility != "private" && published
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Reaction values and the video counter each one feeds
+var reactionCounters = map[string]string{
+	"like":    "likes",
+	"dislike": "dislikes",
+}
+
+// GetMyReaction returns the current user's reaction to a video - GET /api/v1/videos/:id/reaction
+func GetMyReaction(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	video, err := findWatchableVideo(c, database.DB)
+	if err != nil {
+		return err
+	}
+
+	var reaction models.Reaction
+	err = database.DB.Where("user_id = ? AND video_id = ?", userID, video.ID).First(&reaction).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.ErrorResponse(c, "Failed to fetch reaction", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, reactionSummary(video, reaction.Value))
+}
+
+// SetReaction likes or dislikes a video, replacing any previous reaction - PUT /api/v1/videos/:id/reaction
+func SetReaction(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req struct {
+		Value string `json:"value" validate:"required,oneof=like dislike"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	var video *models.Video
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if video, err = findWatchableVideo(c, lockVideo(tx)); err != nil {
+			return err
+		}
+
+		var reaction models.Reaction
+		err = tx.Where("user_id = ? AND video_id = ?", userID, video.ID).First(&reaction).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			reaction = models.Reaction{UserID: userID, VideoID: video.ID, Value: req.Value}
+			if err := tx.Create(&reaction).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case reaction.Value == req.Value:
+			return nil // nothing changes
+		default:
+			if err := adjustReactionCounter(tx, video, reaction.Value, -1); err != nil {
+				return err
+			}
+			if err := tx.Model(&reaction).Update("value", req.Value).Error; err != nil {
+				return err
+			}
+		}
+
+		return adjustReactionCounter(tx, video, req.Value, 1)
+	})
+
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return utils.ErrorResponse(c, "Failed to save reaction", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, reactionSummary(video, req.Value))
+}
+
+// ClearReaction removes the current user's reaction to a video - DELETE /api/v1/videos/:id/reaction
+func ClearReaction(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var video *models.Video
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if video, err = findWatchableVideo(c, lockVideo(tx)); err != nil {
+			return err
+		}
+
+		var reaction models.Reaction
+		err = tx.Where("user_id = ? AND video_id = ?", userID, video.ID).First(&reaction).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // already cleared
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&reaction).Error; err != nil {
+			return err
+		}
+		return adjustReactionCounter(tx, video, reaction.Value, -1)
+	})
+
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return utils.ErrorResponse(c, "Failed to clear reaction", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, reactionSummary(video, ""))
+}
+
+// GetLikedVideos lists the videos the current user liked, most recent like first - GET /api/v1/users/me/likes
+func GetLikedVideos(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	pagination := utils.ParsePagination(c)
+
+	// Videos that went unlisted, private or back to processing since the like are left out
+	query := database.DB.Model(&models.Video{}).
+		Joins("JOIN reactions ON reactions.video_id = videos.id").
+		Where("reactions.user_id = ? AND reactions.value = ?", userID, "like").
+		Where("videos.status = ?", "ready").
+		Scopes(publishedVideos)
+
+	var total int64
+	query.Count(&total)
+
+	var videos []models.Video
+	if err := query.
+		Preload("User").
+		Order("reactions.updated_at DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Find(&videos).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch liked videos", fiber.StatusInternalServerError)
+	}
+
+	// Listing pages need artwork
+	attachThumbnailURLs(videos)
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"videos": videos,
+	}, paginationMeta)
+}
+
+// Locks the video row, so concurrent reactions to it update the counters one at a time
+func lockVideo(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// Adds delta to the counter of a reaction value, on the row and in the loaded video
+func adjustReactionCounter(tx *gorm.DB, video *models.Video, value string, delta int) error {
+	column := reactionCounters[value]
+	if err := tx.Model(video).UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error; err != nil {
+		return err
+	}
+
+	if value == "like" {
+		video.Likes += delta
+	} else {
+		video.Dislikes += delta
+	}
+	return nil
+}
+
+// Reaction state returned by the reaction endpoints (reaction is null when there is none)
+func reactionSummary(video *models.Video, value string) fiber.Map {
+	var reaction interface{}
+	if value != "" {
+		reaction = value
+	}
+
+	return fiber.Map{
+		"video_id": video.ID,
+		"reaction": reaction,
+		"likes":    video.Likes,
+		"dislikes": video.Dislikes,
+	}
+}
@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+	"github.com/alex6damian/GoSport/pkg/storage"
+)
+
+// Content types of the streamed files (the stored object metadata is used for anything else)
+var streamContentTypes = map[string]string{
+	".m3u8": hlsContentType,
+	".ts":   "video/mp2t",
+	".mp4":  "video/mp4",
+	".jpg":  "image/jpeg",
+	".vtt":  "text/vtt; charset=utf-8",
+}
+
+// Whether playback URLs point at the streaming proxy instead of presigned MinIO URLs
+// (MEDIA_PROXY=true, for clients that can't reach MINIO_ENDPOINT)
+func mediaProxyEnabled() bool {
+	return os.Getenv("MEDIA_PROXY") == "true"
+}
+
+// StreamOriginal proxies the uploaded file of a video - GET /api/v1/videos/:id/stream/original
+//
+// The owner (and admins) can watch it before processing is done.
+func StreamOriginal(c *fiber.Ctx) error {
+	var video models.Video
+	if err := database.DB.First(&video, c.Params("id")).Error; err != nil {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	if !canWatchVideo(c, &video) {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	return streamObject(c, video.MinioKey, video.MimeType)
+}
+
+// StreamHLS proxies a playlist or segment of a ready video - GET /api/v1/videos/:id/stream/hls/*
+func StreamHLS(c *fiber.Ctx) error {
+	video, err := findPlayableVideo(c)
+	if err != nil {
+		return err
+	}
+
+	// Keep the request inside the HLS folder of the video
+	file := path.Clean("/" + c.Params("*"))
+	if file == "/" {
+		return utils.ErrorResponse(c, "File not found", fiber.StatusNotFound)
+	}
+
+	return streamObject(c, path.Dir(video.HLSPath)+file, "")
+}
+
+// ServeStoredObject serves the presigned URLs of the local storage driver - GET /storage/*
+func ServeStoredObject(c *fiber.Ctx) error {
+	local, ok := storage.Default.(*storage.Local)
+	if !ok {
+		return utils.ErrorResponse(c, "File not found", fiber.StatusNotFound)
+	}
+
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil || !local.Verify(key, c.Query("expires"), c.Query("signature")) {
+		return utils.ErrorResponse(c, "Invalid or expired URL", fiber.StatusForbidden)
+	}
+
+	return streamObject(c, key, "")
+}
+
+// Streams an object with Range, If-Range and ETag support (a single range per request)
+func streamObject(c *fiber.Ctx, objectName, contentType string) error {
+	info, err := services.GetVideoInfo(objectName)
+	if err != nil {
+		return utils.ErrorResponse(c, "File not found", fiber.StatusNotFound)
+	}
+
+	if ct, ok := streamContentTypes[strings.ToLower(path.Ext(objectName))]; ok {
+		contentType = ct
+	}
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+
+	etag := `"` + strings.Trim(info.ETag, `"`) + `"`
+	lastModified := info.LastModified.UTC().Format(http.TimeFormat)
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, lastModified)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	c.Set(fiber.HeaderContentType, contentType)
+
+	// Conditional GET
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		if etagMatches(match, etag) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+	} else if since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince)); err == nil && !info.LastModified.Truncate(time.Second).After(since) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	start, end := int64(0), info.Size-1
+	status := fiber.StatusOK
+
+	// If-Range: only honor the range when the client's copy is still current
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && !ifRangeMatches(ifRange, etag, lastModified) {
+		rangeHeader = ""
+	}
+
+	if rangeHeader != "" {
+		var ok bool
+		start, end, ok = parseByteRange(rangeHeader, info.Size)
+		if !ok {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+			return utils.ErrorResponse(c, "Requested range not satisfiable", fiber.StatusRequestedRangeNotSatisfiable)
+		}
+		status = fiber.StatusPartialContent
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size))
+	}
+
+	length := end - start + 1
+	c.Status(status)
+
+	if c.Method() == fiber.MethodHead {
+		c.Response().Header.SetContentLength(int(length))
+		return nil
+	}
+
+	if length == 0 {
+		return c.Send(nil)
+	}
+
+	body, err := services.GetObjectRange(objectName, start, end)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Deleted since the Stat above
+		return utils.ErrorResponse(c, "File not found", fiber.StatusNotFound)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to read file", fiber.StatusInternalServerError)
+	}
+
+	// fasthttp closes the body once it's sent
+	return c.SendStream(body, int(length))
+}
+
+// Parses a "bytes=start-end", "bytes=start-" or "bytes=-suffix" header into an inclusive range.
+// Multiple ranges aren't supported, the first one is served.
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+	spec, _, _ = strings.Cut(spec, ",")
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	// Suffix range: the last N bytes
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
+
+// Whether an If-Range header designates the current version: strong ETag comparison (weak ETags never match)
+// or the exact Last-Modified date
+func ifRangeMatches(ifRange, etag, lastModified string) bool {
+	ifRange = strings.TrimSpace(ifRange)
+	if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+	return ifRange == etag || ifRange == lastModified
+}
+
+// Whether an If-None-Match header (list of ETags or "*") matches the current ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package routes
+
+import "testing"
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		size      int64
+		wantStart int64
+		wantEnd   int64
+		wantOK    bool
+	}{
+		// Closed ranges
+		{"first byte", "bytes=0-0", 1000, 0, 0, true},
+		{"first 500 bytes", "bytes=0-499", 1000, 0, 499, true},
+		{"middle", "bytes=500-599", 1000, 500, 599, true},
+		{"last byte", "bytes=999-999", 1000, 999, 999, true},
+		{"end past the size is clamped", "bytes=900-5000", 1000, 900, 999, true},
+		{"spaces around the range", "bytes= 10-20 ", 1000, 10, 20, true},
+
+		// Open-ended ranges
+		{"from offset", "bytes=100-", 1000, 100, 999, true},
+		{"whole file", "bytes=0-", 1000, 0, 999, true},
+		{"from last byte", "bytes=999-", 1000, 999, 999, true},
+
+		// Suffix ranges
+		{"last 500 bytes", "bytes=-500", 1000, 500, 999, true},
+		{"last byte only", "bytes=-1", 1000, 999, 999, true},
+		{"suffix longer than the file", "bytes=-5000", 1000, 0, 999, true},
+		{"empty suffix", "bytes=-0", 1000, 0, 0, false},
+		{"negative suffix", "bytes=--5", 1000, 0, 0, false},
+		{"suffix of an empty file", "bytes=-10", 0, 0, 0, false},
+
+		// Unsatisfiable (416)
+		{"start at size", "bytes=1000-", 1000, 0, 0, false},
+		{"start past size", "bytes=2000-3000", 1000, 0, 0, false},
+		{"empty file", "bytes=0-", 0, 0, 0, false},
+		{"end before start", "bytes=500-100", 1000, 0, 0, false},
+
+		// Multiple ranges: the first one is served
+		{"multi-range", "bytes=0-99,200-299", 1000, 0, 99, true},
+		{"multi-range with suffix first", "bytes=-100, 0-10", 1000, 900, 999, true},
+		{"multi-range unsatisfiable first", "bytes=5000-,0-10", 1000, 0, 0, false},
+
+		// Malformed
+		{"other unit", "items=0-10", 1000, 0, 0, false},
+		{"missing unit", "0-10", 1000, 0, 0, false},
+		{"missing dash", "bytes=100", 1000, 0, 0, false},
+		{"empty spec", "bytes=", 1000, 0, 0, false},
+		{"dash only", "bytes=-", 1000, 0, 0, false},
+		{"not a number", "bytes=a-b", 1000, 0, 0, false},
+		{"negative start", "bytes=-5-10", 1000, 0, 0, false},
+		{"extra dash", "bytes=1-2-3", 1000, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := parseByteRange(tt.header, tt.size)
+			if ok != tt.wantOK {
+				t.Fatalf("parseByteRange(%q, %d) ok = %v, want %v", tt.header, tt.size, ok, tt.wantOK)
+			}
+			if ok && (start != tt.wantStart || end != tt.wantEnd) {
+				t.Errorf("parseByteRange(%q, %d) = %d-%d, want %d-%d", tt.header, tt.size, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	const etag = `"abc123"`
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"same", `"abc123"`, true},
+		{"weak matches (weak comparison)", `W/"abc123"`, true},
+		{"wildcard", `*`, true},
+		{"in a list", `"old", "abc123"`, true},
+		{"weak in a list", `"old",W/"abc123"`, true},
+		{"different", `"other"`, false},
+		{"unquoted", `abc123`, false},
+		{"list without match", `"a", "b"`, false},
+		{"empty", ``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.header, etag); got != tt.want {
+				t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	const (
+		etag         = `"abc123"`
+		lastModified = "Sun, 18 Oct 2026 07:00:00 GMT"
+	)
+
+	tests := []struct {
+		name    string
+		ifRange string
+		want    bool
+	}{
+		{"strong etag", `"abc123"`, true},
+		{"strong etag with spaces", ` "abc123" `, true},
+		{"weak etag never matches", `W/"abc123"`, false},
+		{"other etag", `"old"`, false},
+		{"same date", "Sun, 18 Oct 2026 07:00:00 GMT", true},
+		{"other date", "Sat, 17 Oct 2026 07:00:00 GMT", false},
+		{"wildcard is not allowed", `*`, false},
+		{"list is not allowed", `"old", "abc123"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ifRangeMatches(tt.ifRange, etag, lastModified); got != tt.want {
+				t.Errorf("ifRangeMatches(%q) = %v, want %v", tt.ifRange, got, tt.want)
+			}
+		})
+	}
+}
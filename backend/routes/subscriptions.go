@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Public profile of a user in a subscription listing
+type SubscriptionUserResponse struct {
+	Username     string    `json:"username"`
+	Avatar       string    `json:"avatar,omitempty"`
+	SubscribedAt time.Time `json:"subscribed_at"`
+}
+
+// Subscribe subscribes the current user to a creator - POST /api/v1/users/:username/subscribe
+func Subscribe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	creator, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if creator.ID == userID {
+		return utils.ErrorResponse(c, "You can't subscribe to yourself", fiber.StatusBadRequest)
+	}
+
+	// Subscribing twice keeps the first subscription
+	subscription := models.Subscription{SubscriberID: userID, CreatorID: creator.ID}
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription)
+	if result.Error != nil {
+		return utils.ErrorResponse(c, "Failed to subscribe", fiber.StatusInternalServerError)
+	}
+
+	status := fiber.StatusCreated
+	if result.RowsAffected == 0 {
+		status = fiber.StatusOK
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"message":           "Subscribed to " + creator.Username,
+			"subscribers_count": countSubscribers(creator.ID),
+		},
+	})
+}
+
+// Unsubscribe removes the current user's subscription to a creator - DELETE /api/v1/users/:username/subscribe
+func Unsubscribe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	creator, err := findUserByUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Where("subscriber_id = ? AND creator_id = ?", userID, creator.ID).
+		Delete(&models.Subscription{}).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to unsubscribe", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message":           "Unsubscribed from " + creator.Username,
+		"subscribers_count": countSubscribers(creator.ID),
+	})
+}
+
+// GetMySubscriptions lists the creators the current user follows - GET /api/v1/users/me/subscriptions
+func GetMySubscriptions(c *fiber.Ctx) error {
+	return listSubscriptionUsers(c, "subscriber_id", "creator_id", "subscriptions")
+}
+
+// GetMySubscribers lists the users following the current user - GET /api/v1/users/me/subscribers
+func GetMySubscribers(c *fiber.Ctx) error {
+	return listSubscriptionUsers(c, "creator_id", "subscriber_id", "subscribers")
+}
+
+// GetSubscriptionFeed lists the latest ready videos of the followed creators - GET /api/v1/feed/subscriptions
+func GetSubscriptionFeed(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	pagination := utils.ParsePagination(c)
+
+	followed := database.DB.Model(&models.Subscription{}).Select("creator_id").Where("subscriber_id = ?", userID)
+
+	// Only published public videos, whatever the role: scheduled ones show up at their publishing date
+	query := database.DB.Model(&models.Video{}).
+		Where("videos.user_id IN (?) AND videos.status = ?", followed, "ready").
+		Scopes(publishedVideos)
+
+	var total int64
+	query.Count(&total)
+
+	var videos []models.Video
+	if err := query.
+		Preload("User").
+		Order("COALESCE(videos.publish_at, videos.created_at) DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Find(&videos).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch feed", fiber.StatusInternalServerError)
+	}
+
+	// Listing pages need artwork
+	attachThumbnailURLs(videos)
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"videos": videos,
+	}, paginationMeta)
+}
+
+// Lists the users on the other side of the current user's subscriptions, most recent first
+func listSubscriptionUsers(c *fiber.Ctx, ownColumn, otherColumn, key string) error {
+	userID := c.Locals("userID").(uint)
+	pagination := utils.ParsePagination(c)
+
+	// Deleted accounts are left out
+	query := database.DB.Model(&models.Subscription{}).
+		Joins("JOIN users ON users.id = subscriptions."+otherColumn+" AND users.deleted_at IS NULL").
+		Where("subscriptions."+ownColumn+" = ?", userID)
+
+	var total int64
+	query.Count(&total)
+
+	users := make([]SubscriptionUserResponse, 0, pagination.Limit)
+	if err := query.
+		Select("users.username, users.avatar, subscriptions.created_at AS subscribed_at").
+		Order("subscriptions.created_at DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Scan(&users).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch "+key, fiber.StatusInternalServerError)
+	}
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		key: users,
+	}, paginationMeta)
+}
+
+// Loads the :username user (errors are *fiber.Error, rendered by the error handler)
+func findUserByUsername(c *fiber.Ctx) (*models.User, error) {
+	var user models.User
+	err := database.DB.Where("username = ?", c.Params("username")).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	return &user, nil
+}
+
+func countSubscribers(creatorID uint) int64 {
+	var count int64
+	database.DB.Model(&models.Subscription{}).Where("creator_id = ?", creatorID).Count(&count)
+	return count
+}
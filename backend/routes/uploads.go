@@ -0,0 +1,468 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+	"github.com/alex6damian/GoSport/pkg/storage"
+)
+
+const (
+	uploadChunkSize       = 16 * 1024 * 1024        // 16 MB (MinIO parts must be at least 5 MB, except the last one)
+	maxResumableVideoSize = 20 * 1024 * 1024 * 1024 // 20 GB
+	uploadExpiry          = 24 * time.Hour          // Unfinished uploads are aborted after this
+	presignedUploadExpiry = 1 * time.Hour           // Validity of the presigned POST policy
+)
+
+// Byte range received so far (inclusive)
+type uploadRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// InitUpload starts a resumable upload - POST /api/v1/videos/uploads
+func InitUpload(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req struct {
+		FileName    string `json:"filename" validate:"required"`
+		FileSize    int64  `json:"size" validate:"required,gt=0"`
+		ContentType string `json:"content_type"`
+		Title       string `json:"title" validate:"required"`
+		Description string `json:"description"`
+		Sport       string `json:"sport"`
+		Visibility  string `json:"visibility"`
+		PublishAt   string `json:"publish_at"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	visibility, publishAt, err := parsePublishing(req.Visibility, req.PublishAt)
+	if err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	// Validate file size
+	if req.FileSize > maxResumableVideoSize {
+		return utils.ErrorResponse(c, fmt.Sprintf("File too large. Max size: %d GB", maxResumableVideoSize/(1024*1024*1024)), fiber.StatusBadRequest)
+	}
+
+	// Validate file extension
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	if !allowedVideoFormats[ext] {
+		return utils.ErrorResponse(c, "Invalid file format. Allowed: mp4, mov, avi, mkv, webm", fiber.StatusBadRequest)
+	}
+
+	minioKey := services.NewVideoObjectName(req.FileName)
+	minioUploadID, err := services.StartMultipartUpload(minioKey, req.ContentType)
+	if err != nil {
+		return utils.ErrorResponse(c, fmt.Sprintf("Failed to start upload: %v", err), fiber.StatusInternalServerError)
+	}
+
+	upload := models.Upload{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Method:        "chunked",
+		MinioKey:      minioKey,
+		MinioUploadID: minioUploadID,
+		FileName:      req.FileName,
+		FileSize:      req.FileSize, // MimeType is detected from the first chunk
+		ChunkSize:     uploadChunkSize,
+		TotalChunks:   int((req.FileSize + uploadChunkSize - 1) / uploadChunkSize),
+		Title:         req.Title,
+		Description:   req.Description,
+		Sport:         req.Sport,
+		Visibility:    visibility,
+		PublishAt:     publishAt,
+		Status:        "active",
+		ExpiresAt:     time.Now().Add(uploadExpiry),
+	}
+
+	if err := database.DB.Create(&upload).Error; err != nil {
+		services.AbortMultipartUpload(minioKey, minioUploadID)
+		return utils.ErrorResponse(c, "Failed to create upload", fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    upload,
+	})
+}
+
+// InitPresignedUpload issues a presigned POST policy, so the file goes straight to MinIO - POST /api/v1/videos/uploads/presigned
+//
+// The client POSTs the returned fields + the file (last form field "file") to the URL, then calls the complete endpoint.
+func InitPresignedUpload(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req struct {
+		FileName    string `json:"filename" validate:"required"`
+		FileSize    int64  `json:"size" validate:"required,gt=0"`
+		ContentType string `json:"content_type" validate:"required"`
+		Title       string `json:"title" validate:"required"`
+		Description string `json:"description"`
+		Sport       string `json:"sport"`
+		Visibility  string `json:"visibility"`
+		PublishAt   string `json:"publish_at"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	visibility, publishAt, err := parsePublishing(req.Visibility, req.PublishAt)
+	if err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
+	// Validate file size
+	if req.FileSize > maxResumableVideoSize {
+		return utils.ErrorResponse(c, fmt.Sprintf("File too large. Max size: %d GB", maxResumableVideoSize/(1024*1024*1024)), fiber.StatusBadRequest)
+	}
+
+	// Validate file extension
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	if !allowedVideoFormats[ext] {
+		return utils.ErrorResponse(c, "Invalid file format. Allowed: mp4, mov, avi, mkv, webm", fiber.StatusBadRequest)
+	}
+
+	// The policy pins the content type, so it has to be a video one
+	if !strings.HasPrefix(req.ContentType, "video/") {
+		return utils.ErrorResponse(c, "Content type must be a video type", fiber.StatusBadRequest)
+	}
+
+	minioKey := services.NewVideoObjectName(req.FileName)
+	url, fields, err := services.PresignedUploadPolicy(minioKey, req.ContentType, req.FileSize, presignedUploadExpiry)
+	if errors.Is(err, storage.ErrNotSupported) {
+		return utils.ErrorResponse(c, "Presigned uploads are not supported by the storage driver, use resumable uploads", fiber.StatusNotImplemented)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fmt.Sprintf("Failed to presign upload: %v", err), fiber.StatusInternalServerError)
+	}
+
+	upload := models.Upload{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Method:      "presigned",
+		MinioKey:    minioKey,
+		FileName:    req.FileName,
+		FileSize:    req.FileSize,
+		MimeType:    req.ContentType,
+		Title:       req.Title,
+		Description: req.Description,
+		Sport:       req.Sport,
+		Visibility:  visibility,
+		PublishAt:   publishAt,
+		Status:      "active",
+		ExpiresAt:   time.Now().Add(uploadExpiry),
+	}
+
+	if err := database.DB.Create(&upload).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to create upload", fiber.StatusInternalServerError)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"upload":     upload,
+			"url":        url,
+			"fields":     fields,
+			"expires_at": time.Now().Add(presignedUploadExpiry),
+		},
+	})
+}
+
+// UploadChunk stores one chunk of an upload, re-sending a chunk replaces it - PUT /api/v1/videos/uploads/:uploadId/chunks/:index
+func UploadChunk(c *fiber.Ctx) error {
+	upload, err := findActiveUpload(c)
+	if err != nil {
+		return err
+	}
+
+	if upload.Method != "chunked" {
+		return utils.ErrorResponse(c, "Presigned uploads go straight to storage", fiber.StatusBadRequest)
+	}
+
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil || index < 0 || index >= upload.TotalChunks {
+		return utils.ErrorResponse(c, fmt.Sprintf("Chunk index must be between 0 and %d", upload.TotalChunks-1), fiber.StatusBadRequest)
+	}
+
+	// Every chunk has the announced size, except the last one which holds the remainder
+	data := c.Body()
+	expectedSize := chunkSize(upload, index)
+	if int64(len(data)) != expectedSize {
+		return utils.ErrorResponse(c, fmt.Sprintf("Chunk %d must be %d bytes, got %d", index, expectedSize, len(data)), fiber.StatusBadRequest)
+	}
+
+	// The first chunk holds the magic bytes of the container
+	if index == 0 {
+		mimeType, ok := utils.DetectVideoType(data[:min(len(data), utils.VideoSniffLength)])
+		if !ok {
+			return utils.ErrorResponse(c, "File is not a supported video. Allowed: mp4, mov, avi, mkv, webm", fiber.StatusBadRequest)
+		}
+		if err := database.DB.Model(upload).Update("mime_type", mimeType).Error; err != nil {
+			return utils.ErrorResponse(c, "Database error", fiber.StatusInternalServerError)
+		}
+	}
+
+	partNumber := index + 1
+	etag, err := services.UploadPart(upload.MinioKey, upload.MinioUploadID, partNumber, data)
+	if err != nil {
+		return utils.ErrorResponse(c, fmt.Sprintf("Failed to store chunk: %v", err), fiber.StatusInternalServerError)
+	}
+
+	part := models.UploadPart{
+		UploadID:   upload.ID,
+		PartNumber: partNumber,
+		Size:       int64(len(data)),
+		ETag:       etag,
+	}
+
+	err = database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "upload_id"}, {Name: "part_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "e_tag"}),
+	}).Create(&part).Error
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to save chunk", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"index": index,
+		"size":  part.Size,
+	})
+}
+
+// GetUploadStatus reports which chunks were received, so an interrupted upload can be resumed - GET /api/v1/videos/uploads/:uploadId
+func GetUploadStatus(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var upload models.Upload
+	if err := database.DB.Preload("Parts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("part_number")
+	}).Where("id = ? AND user_id = ?", c.Params("uploadId"), userID).First(&upload).Error; err != nil {
+		return utils.ErrorResponse(c, "Upload not found", fiber.StatusNotFound)
+	}
+
+	received := make(map[int]bool, len(upload.Parts))
+	receivedChunks := make([]int, 0, len(upload.Parts))
+	var receivedBytes int64
+	for _, part := range upload.Parts {
+		received[part.PartNumber-1] = true
+		receivedChunks = append(receivedChunks, part.PartNumber-1)
+		receivedBytes += part.Size
+	}
+
+	missingChunks := make([]int, 0)
+	ranges := make([]uploadRange, 0)
+	for index := 0; index < upload.TotalChunks; index++ {
+		if !received[index] {
+			missingChunks = append(missingChunks, index)
+			continue
+		}
+
+		// Merge adjacent chunks into a single range
+		start := int64(index) * upload.ChunkSize
+		end := start + chunkSize(&upload, index) - 1
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 == start {
+			ranges[n-1].End = end
+		} else {
+			ranges = append(ranges, uploadRange{Start: start, End: end})
+		}
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"upload":          upload,
+		"received_bytes":  receivedBytes,
+		"received_chunks": receivedChunks,
+		"received_ranges": ranges,
+		"missing_chunks":  missingChunks,
+	})
+}
+
+// CompleteUpload assembles the chunks (or verifies the presigned upload) and queues the video for processing - POST /api/v1/videos/uploads/:uploadId/complete
+func CompleteUpload(c *fiber.Ctx) error {
+	upload, err := findActiveUpload(c)
+	if err != nil {
+		return err
+	}
+
+	var parts []models.UploadPart
+	if upload.Method == "chunked" {
+		if err := database.DB.Where("upload_id = ?", upload.ID).Find(&parts).Error; err != nil {
+			return utils.ErrorResponse(c, "Database error", fiber.StatusInternalServerError)
+		}
+
+		if len(parts) != upload.TotalChunks {
+			return utils.ErrorResponse(c, fmt.Sprintf("Upload incomplete: received %d of %d chunks", len(parts), upload.TotalChunks), fiber.StatusConflict)
+		}
+	}
+
+	// Claim the upload so concurrent complete requests don't create the video twice
+	result := database.DB.Model(&models.Upload{}).
+		Where("id = ? AND status = ?", upload.ID, "active").
+		Update("status", "completing")
+	if result.Error != nil {
+		return utils.ErrorResponse(c, "Database error", fiber.StatusInternalServerError)
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, "Upload is already being completed", fiber.StatusConflict)
+	}
+
+	if upload.Method == "presigned" {
+		if err := verifyPresignedUpload(c, upload); err != nil {
+			return err
+		}
+	} else if err := services.CompleteMultipartUpload(upload.MinioKey, upload.MinioUploadID, parts); err != nil {
+		database.DB.Model(upload).Update("status", "active")
+		return utils.ErrorResponse(c, fmt.Sprintf("Failed to assemble upload: %v", err), fiber.StatusInternalServerError)
+	}
+
+	video := models.Video{
+		Title:       upload.Title,
+		Description: upload.Description,
+		Sport:       upload.Sport,
+		Visibility:  upload.Visibility,
+		PublishAt:   upload.PublishAt,
+		UserID:      upload.UserID,
+		MinioKey:    upload.MinioKey,
+		FileName:    upload.FileName,
+		FileSize:    upload.FileSize,
+		MimeType:    upload.MimeType,
+		Status:      "pending",
+	}
+
+	if err := createVideoWithJob(&video); err != nil {
+		// The multipart upload is gone at this point, so the upload can't be resumed
+		services.DeleteVideo(upload.MinioKey)
+		database.DB.Model(upload).Update("status", "aborted")
+		return utils.ErrorResponse(c, "Failed to save video", fiber.StatusInternalServerError)
+	}
+
+	database.DB.Model(upload).Updates(map[string]interface{}{
+		"status":   "completed",
+		"video_id": video.ID,
+	})
+	database.DB.Where("upload_id = ?", upload.ID).Delete(&models.UploadPart{})
+
+	// Load user info
+	database.DB.Preload("User").First(&video, video.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Video uploaded successfully",
+		"data":    video,
+	})
+}
+
+// AbortUpload cancels an upload and discards the received chunks - DELETE /api/v1/videos/uploads/:uploadId
+func AbortUpload(c *fiber.Ctx) error {
+	upload, err := findActiveUpload(c)
+	if err != nil {
+		return err
+	}
+
+	if upload.Method == "presigned" {
+		if err := services.DeleteVideo(upload.MinioKey); err != nil {
+			log.Printf("Failed to delete presigned upload %s: %v", upload.ID, err)
+		}
+	} else if err := services.AbortMultipartUpload(upload.MinioKey, upload.MinioUploadID); err != nil {
+		log.Printf("Failed to abort multipart upload %s: %v", upload.ID, err)
+	}
+
+	database.DB.Model(upload).Update("status", "aborted")
+	database.DB.Where("upload_id = ?", upload.ID).Delete(&models.UploadPart{})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Upload aborted",
+	})
+}
+
+// Checks that the client really stored the announced file, the upload must be claimed ("completing")
+// (errors are *fiber.Error, rendered by the error handler)
+func verifyPresignedUpload(c *fiber.Ctx, upload *models.Upload) error {
+	info, err := services.GetVideoInfo(upload.MinioKey)
+	if err != nil {
+		// Not uploaded yet (or the upload is still running), the client can finalize again later
+		database.DB.Model(upload).Update("status", "active")
+		return fiber.NewError(fiber.StatusConflict, "File has not been uploaded yet")
+	}
+
+	// The policy enforces both, but a mismatch means the object can't be trusted
+	if info.Size != upload.FileSize || info.ContentType != upload.MimeType {
+		services.DeleteVideo(upload.MinioKey)
+		database.DB.Model(upload).Update("status", "aborted")
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Uploaded file does not match the announced one (%d bytes, %s)", info.Size, info.ContentType))
+	}
+
+	// The content type is the client's claim, validate the container by its magic bytes
+	header, err := services.ReadObjectHead(upload.MinioKey, utils.VideoSniffLength)
+	if err != nil {
+		database.DB.Model(upload).Update("status", "active")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to read uploaded file")
+	}
+
+	mimeType, ok := utils.DetectVideoType(header)
+	if !ok {
+		services.DeleteVideo(upload.MinioKey)
+		database.DB.Model(upload).Update("status", "aborted")
+		return fiber.NewError(fiber.StatusBadRequest, "File is not a supported video. Allowed: mp4, mov, avi, mkv, webm")
+	}
+	upload.MimeType = mimeType
+
+	return nil
+}
+
+// Loads the :uploadId upload of the authenticated user, it must still accept chunks
+// (errors are *fiber.Error, rendered by the error handler)
+func findActiveUpload(c *fiber.Ctx) (*models.Upload, error) {
+	userID := c.Locals("userID").(uint)
+
+	var upload models.Upload
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("uploadId"), userID).First(&upload).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+	}
+
+	if upload.Status != "active" {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Upload is %s", upload.Status))
+	}
+
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, fiber.NewError(fiber.StatusGone, "Upload expired")
+	}
+
+	return &upload, nil
+}
+
+// Expected size of a chunk (the last one holds the remainder)
+func chunkSize(upload *models.Upload, index int) int64 {
+	if index == upload.TotalChunks-1 {
+		return upload.FileSize - int64(index)*upload.ChunkSize
+	}
+	return upload.ChunkSize
+}
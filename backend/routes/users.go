@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"time"
+
+	"github.com/alex6damian/GoSport/backend/services"
 	"github.com/alex6damian/GoSport/backend/utils"
 	"github.com/alex6damian/GoSport/pkg/database"
 	"github.com/alex6damian/GoSport/pkg/models"
@@ -15,6 +18,23 @@ type UpdateProfileRequest struct {
 	Avatar   string `json:"avatar" validate:"omitempty,url"`
 }
 
+// Password change structure
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,strong_password"`
+}
+
+// Open login session of the authenticated user
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"` // session of the request
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 // Complete user profile response structure
 type UserProfileResponse struct {
 	ID               uint   `json:"id"`
@@ -22,6 +42,7 @@ type UserProfileResponse struct {
 	Email            string `json:"email"`
 	Role             string `json:"role"`
 	Avatar           string `json:"avatar,omitempty"`
+	Verified         bool   `json:"verified,omitempty"`
 	VideosCount      int64  `json:"videos_count,omitempty"`
 	SubscribersCount int64  `json:"subscribers_count,omitempty"`
 	CreatedAt        string `json:"created_at"`
@@ -54,6 +75,7 @@ func GetMyProfile(c *fiber.Ctx) error {
 		Email:            user.Email,
 		Role:             user.Role,
 		Avatar:           user.Avatar,
+		Verified:         user.Verified,
 		VideosCount:      videosCount,
 		SubscribersCount: subscribersCount,
 		CreatedAt:        user.CreatedAt.Format("2006-01-02T15:04:05Z"),
@@ -112,6 +134,7 @@ func UpdateMyProfile(c *fiber.Ctx) error {
 		Email:            user.Email,
 		Role:             user.Role,
 		Avatar:           user.Avatar,
+		Verified:         user.Verified,
 		VideosCount:      videosCount,
 		SubscribersCount: subscribersCount,
 		CreatedAt:        user.CreatedAt.Format("2006-01-02T15:04:05Z"),
@@ -120,6 +143,115 @@ func UpdateMyProfile(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, response)
 }
 
+// PUT /api/v1/users/me/password -> Change authenticated user's password
+//
+// Every session is revoked (other devices are logged out), the response carries the tokens of a new one.
+func ChangeMyPassword(c *fiber.Ctx) error {
+	// Get user ID from auth middleware
+	userID := c.Locals("userID").(uint)
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ValidationErrorResponse(c, map[string]string{"body": "Invalid request body"})
+	}
+
+	// Validate input
+	if err := utils.ValidateStruct(req); err != nil {
+		return utils.ValidationErrorResponse(c, map[string]string{"validation": err.Error()})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return utils.ErrorResponse(c, "User not found", fiber.StatusNotFound)
+	}
+
+	// Check current password
+	if !utils.CheckPassword(user.Password, req.CurrentPassword) {
+		return utils.ErrorResponse(c, "Current password is incorrect", fiber.StatusUnauthorized)
+	}
+
+	if err := services.ChangePassword(database.DB, &user, req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, "Failed to change password", fiber.StatusInternalServerError)
+	}
+
+	tokens, err := services.StartSession(database.DB, &user, c.IP(), c.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to generate token", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Password changed successfully, other sessions were logged out",
+		"tokens":  tokens,
+	})
+}
+
+// GET /api/v1/users/me/sessions -> Open sessions and login history (paginated) of the authenticated user
+func GetMySessions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	sessionID, _ := c.Locals("sessionID").(string)
+	pagination := utils.ParsePagination(c)
+
+	var sessions []models.Session
+	if err := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch sessions", fiber.StatusInternalServerError)
+	}
+
+	openSessions := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		openSessions = append(openSessions, SessionResponse{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			Current:    session.ID == sessionID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	// Login attempts on the account, failed ones included
+	var total int64
+	database.DB.Model(&models.LoginEvent{}).Where("user_id = ?", userID).Count(&total)
+
+	var events []models.LoginEvent
+	if err := database.DB.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(pagination.Limit).
+		Offset(pagination.Offset).
+		Find(&events).Error; err != nil {
+		return utils.ErrorResponse(c, "Failed to fetch login history", fiber.StatusInternalServerError)
+	}
+
+	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"sessions":      openSessions,
+		"login_history": events,
+	}, paginationMeta)
+}
+
+// DELETE /api/v1/users/me/sessions/:id -> Revoke one of the authenticated user's sessions (e.g., a lost device)
+func RevokeMySession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&session).Error; err != nil {
+		return utils.ErrorResponse(c, "Session not found", fiber.StatusNotFound)
+	}
+
+	if err := services.RevokeSession(database.DB, session.ID, "logout"); err != nil {
+		return utils.ErrorResponse(c, "Failed to revoke session", fiber.StatusInternalServerError)
+	}
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"message": "Session revoked successfully",
+	})
+}
+
 // GET /api/v1/users/:username -> Get user profile by username
 func GetUserProfileByUsername(c *fiber.Ctx) error {
 	username := c.Params("username")
@@ -168,14 +300,15 @@ func GetUserVideos(c *fiber.Ctx) error {
 	// Parse pagination
 	pagination := utils.ParsePagination(c)
 
-	// Get total count
+	// Get total count (the owner and admins also see unlisted, private and scheduled videos)
 	var total int64
-	database.DB.Model(&models.Video{}).Where("user_id = ? AND status = ?", user.ID, "ready").Count(&total)
+	database.DB.Model(&models.Video{}).Where("user_id = ? AND status = ?", user.ID, "ready").Scopes(visibleVideos(c)).Count(&total)
 
 	// Get videos
 	var videos []models.Video
 	if err := database.DB.
 		Where("user_id = ? AND status = ?", user.ID, "ready").
+		Scopes(visibleVideos(c)).
 		Order("created_at DESC").
 		Limit(pagination.Limit).
 		Offset(pagination.Offset).
@@ -183,6 +316,9 @@ func GetUserVideos(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "Failed to fetch videos", fiber.StatusInternalServerError)
 	}
 
+	// Listing pages need artwork
+	attachThumbnailURLs(videos)
+
 	// Create pagination metadata
 	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
 
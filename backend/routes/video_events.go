@@ -0,0 +1,158 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+const (
+	eventsFallbackPoll = 5 * time.Second  // How often the job row is re-read while job changes aren't pushed (listener down)
+	eventsKeepAlive    = 15 * time.Second // Comment line sent when nothing changed (keeps proxies from closing the stream)
+	eventsMaxDuration  = 1 * time.Hour    // Streams are closed after this, EventSource reconnects on its own
+)
+
+// Job statuses after which nothing will change anymore
+var terminalJobStatuses = map[string]bool{
+	"completed": true,
+	"failed":    true,
+	"dead":      true,
+}
+
+// StreamVideoProcessing pushes processing status and progress as server-sent events - GET /api/v1/videos/:id/processing/events
+//
+// Events: "status" (job status changed), "progress" (stage/percentage/ETA changed), "end" (job reached completed, failed or dead)
+func StreamVideoProcessing(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	videoID := c.Params("id")
+
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	// Check ownership
+	if video.UserID != userID {
+		return utils.ErrorResponse(c, "You don't have permission to view this video's processing", fiber.StatusForbidden)
+	}
+
+	var job models.ProcessingJob
+	if err := database.DB.Where("video_id = ?", video.ID).Order("id DESC").First(&job).Error; err != nil {
+		return utils.ErrorResponse(c, "No processing job found for this video", fiber.StatusNotFound)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+
+	// The stream outlives the handler, so only plain values are captured
+	id := video.ID
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		streamProcessingEvents(w, id)
+	}))
+
+	return nil
+}
+
+// Writes an event every time the latest job of the video changes, until it reaches a terminal status.
+// The job is re-read when the worker notifies a change (see services.ListenForJobProgress).
+func streamProcessingEvents(w *bufio.Writer, videoID uint) {
+	changed, unsubscribe := services.SubscribeJobProgress(videoID)
+	defer unsubscribe()
+
+	var lastStatus string
+	var lastUpdate time.Time
+	lastWrite := time.Now()
+	deadline := time.NewTimer(eventsMaxDuration)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(eventsFallbackPoll)
+	defer ticker.Stop()
+
+	for {
+		var video models.Video
+		var job models.ProcessingJob
+		if err := database.DB.First(&video, videoID).Error; err != nil {
+			writeEvent(w, "error", fiber.Map{"error": "Video not found"})
+			w.Flush()
+			return
+		}
+		if err := database.DB.Where("video_id = ?", videoID).Order("id DESC").First(&job).Error; err != nil {
+			writeEvent(w, "error", fiber.Map{"error": "No processing job found for this video"})
+			w.Flush()
+			return
+		}
+
+		switch {
+		case job.Status != lastStatus:
+			writeEvent(w, "status", processingStatus(&video, &job))
+			lastWrite = time.Now()
+		case !job.UpdatedAt.Equal(lastUpdate):
+			writeEvent(w, "progress", processingStatus(&video, &job))
+			lastWrite = time.Now()
+		}
+		lastStatus = job.Status
+		lastUpdate = job.UpdatedAt
+
+		if terminalJobStatuses[job.Status] {
+			writeEvent(w, "end", fiber.Map{"status": job.Status})
+			w.Flush()
+			return
+		}
+
+		// Flush fails once the client went away
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		// Wait for a change, only polling while changes aren't pushed
+		if !waitForJobChange(w, changed, ticker.C, deadline.C, &lastWrite) {
+			return
+		}
+	}
+}
+
+// Blocks until the job should be re-read, writing keep-alives meanwhile.
+// Returns false once the stream should end (deadline reached or client gone).
+func waitForJobChange(w *bufio.Writer, changed <-chan struct{}, tick, deadline <-chan time.Time, lastWrite *time.Time) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-deadline:
+			return false
+		case <-tick:
+			if !services.JobProgressListening() {
+				return true
+			}
+			if time.Since(*lastWrite) >= eventsKeepAlive {
+				fmt.Fprint(w, ": keep-alive\n\n")
+				*lastWrite = time.Now()
+				if err := w.Flush(); err != nil {
+					return false
+				}
+			}
+		}
+	}
+}
+
+// Writes a single SSE event with a JSON payload
+func writeEvent(w *bufio.Writer, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
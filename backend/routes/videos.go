@@ -2,7 +2,9 @@ package routes
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -28,6 +30,13 @@ var allowedVideoFormats = map[string]bool{
 // Max video size: 100 MB
 const maxVideoSize = 100 * 1024 * 1024
 
+// Video visibilities
+var videoVisibilities = map[string]bool{
+	"public":   true, // listed everywhere
+	"unlisted": true, // anyone with the link, never listed
+	"private":  true, // owner (and admins) only
+}
+
 // UploadVideo handles video file upload - POST /api/v1/videos
 func UploadVideo(c *fiber.Ctx) error {
 	// Get authenticated user
@@ -68,6 +77,12 @@ func UploadVideo(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "Title is required", fiber.StatusBadRequest)
 	}
 
+	// Publishing settings (public right after processing by default)
+	visibility, publishAt, err := parsePublishing(c.FormValue("visibility"), c.FormValue("publish_at"))
+	if err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+
 	// Open file
 	fileHeader, err := file.Open()
 	if err != nil {
@@ -75,8 +90,22 @@ func UploadVideo(c *fiber.Ctx) error {
 	}
 	defer fileHeader.Close()
 
+	// Validate the container by its magic bytes (the extension and Content-Type are only claims)
+	header := make([]byte, utils.VideoSniffLength)
+	n, err := io.ReadFull(fileHeader, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return utils.ErrorResponse(c, "Failed to read file", fiber.StatusInternalServerError)
+	}
+	mimeType, ok := utils.DetectVideoType(header[:n])
+	if !ok {
+		return utils.ErrorResponse(c, "File is not a supported video. Allowed: mp4, mov, avi, mkv, webm", fiber.StatusBadRequest)
+	}
+	if _, err := fileHeader.Seek(0, io.SeekStart); err != nil {
+		return utils.ErrorResponse(c, "Failed to read file", fiber.StatusInternalServerError)
+	}
+
 	// Upload to MinIO
-	minioKey, err := services.UploadVideo(fileHeader, file.Filename, file.Size, file.Header.Get("Content-Type"))
+	minioKey, err := services.UploadVideo(fileHeader, file.Filename, file.Size, mimeType)
 	if err != nil {
 		return utils.ErrorResponse(c, fmt.Sprintf("Failed to upload video: %v", err), fiber.StatusInternalServerError)
 	}
@@ -86,33 +115,22 @@ func UploadVideo(c *fiber.Ctx) error {
 		Title:       title,
 		Description: description,
 		Sport:       sport,
+		Visibility:  visibility,
+		PublishAt:   publishAt,
 		UserID:      userID,
 		MinioKey:    minioKey,
 		FileName:    file.Filename,
 		FileSize:    file.Size,
-		MimeType:    file.Header.Get("Content-Type"),
+		MimeType:    mimeType,
 		Status:      "pending", // "ready" for simplicity, in real app this would be "pending" and a background worker would process it
 	}
 
-	if err := database.DB.Create(&video).Error; err != nil {
+	if err := createVideoWithJob(&video); err != nil {
 		// Cleanup: delete from MinIO if DB insert fails
 		services.DeleteVideo(minioKey)
 		return utils.ErrorResponse(c, "Failed to save video", fiber.StatusInternalServerError)
 	}
 
-	// Create processing job
-	processingJob := models.ProcessingJob{
-		VideoID: video.ID,
-		Status:  "queued",
-	}
-
-	if err := database.DB.Create(&processingJob).Error; err != nil {
-		// Cleanup: delete video record and MinIO file if job creation fails
-		database.DB.Delete(&video)
-		services.DeleteVideo(minioKey)
-		return utils.ErrorResponse(c, "Failed to create processing job", fiber.StatusInternalServerError)
-	}
-
 	// Load user info
 	database.DB.Preload("User").First(&video, video.ID)
 
@@ -123,6 +141,74 @@ func UploadVideo(c *fiber.Ctx) error {
 	})
 }
 
+// Validates publishing settings: visibility defaults to public, publish_at (RFC 3339) schedules the video
+func parsePublishing(visibility, publishAt string) (string, *time.Time, error) {
+	if visibility == "" {
+		visibility = "public"
+	}
+	if !videoVisibilities[visibility] {
+		return "", nil, fmt.Errorf("visibility must be one of: public, unlisted, private")
+	}
+
+	if publishAt == "" {
+		return visibility, nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, publishAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("publish_at must be an RFC 3339 date (e.g., 2026-06-11T19:00:00Z)")
+	}
+
+	return visibility, &at, nil
+}
+
+// Restricts a video query to listed videos: public and published (unlisted, private and scheduled ones
+// only show up on their owner's pages, never in listings)
+func publishedVideos(db *gorm.DB) *gorm.DB {
+	return db.Where("videos.visibility = ? AND (videos.publish_at IS NULL OR videos.publish_at <= ?)", "public", time.Now())
+}
+
+// Restricts a video query to what the requesting user may see on a channel page (see middleware.OptionalAuth):
+// published public videos and their own videos, admins see everything
+func visibleVideos(c *fiber.Ctx) func(db *gorm.DB) *gorm.DB {
+	userID, _ := c.Locals("userID").(uint)
+	role, _ := c.Locals("userRole").(string)
+
+	return func(db *gorm.DB) *gorm.DB {
+		if role == "admin" {
+			return db
+		}
+		return db.Where("(videos.visibility = ? AND (videos.publish_at IS NULL OR videos.publish_at <= ?)) OR videos.user_id = ?",
+			"public", time.Now(), userID)
+	}
+}
+
+// Creates the video row and its queued processing job, then wakes up the worker
+func createVideoWithJob(video *models.Video) error {
+	var processingJob models.ProcessingJob
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(video).Error; err != nil {
+			return err
+		}
+
+		processingJob = models.ProcessingJob{
+			VideoID: video.ID,
+			Status:  "queued",
+		}
+		return tx.Create(&processingJob).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Wake up the video worker right away (it falls back to polling if this is missed)
+	if err := database.NotifyJobQueued(database.DB, processingJob.ID); err != nil {
+		log.Printf("Failed to notify workers about job ID %d: %v", processingJob.ID, err)
+	}
+
+	return nil
+}
+
 // ListVideos lists all videos with pagination and filters - GET /api/v1/videos
 func ListVideos(c *fiber.Ctx) error {
 	// Parse pagination
@@ -135,7 +221,7 @@ func ListVideos(c *fiber.Ctx) error {
 	sport := c.Query("sport")
 
 	// Build query
-	query := database.DB.Model(&models.Video{}).Where("status = ?", "ready")
+	query := database.DB.Model(&models.Video{}).Where("status = ?", "ready").Scopes(publishedVideos)
 
 	// Apply sport filter
 	if sport != "" {
@@ -168,6 +254,9 @@ func ListVideos(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "Failed to fetch videos", fiber.StatusInternalServerError)
 	}
 
+	// Listing pages need artwork
+	attachThumbnailURLs(videos)
+
 	// Create pagination metadata
 	paginationMeta := utils.CreatePaginationMeta(pagination.Page, pagination.Limit, total)
 
@@ -181,7 +270,12 @@ func GetVideo(c *fiber.Ctx) error {
 	videoID := c.Params("id")
 
 	var video models.Video
-	if err := database.DB.Preload("User").Preload("Comments").First(&video, videoID).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("MediaInfo").First(&video, videoID).Error; err != nil {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	// Private, scheduled and unprocessed videos only exist for their owner
+	if !canWatchVideo(c, &video) {
 		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
 	}
 
@@ -190,6 +284,9 @@ func GetVideo(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorResponse(c, "Failed to generate video URL", fiber.StatusInternalServerError)
 	}
+	if mediaProxyEnabled() {
+		videoURL = fmt.Sprintf("/api/v1/videos/%d/stream/original", video.ID)
+	}
 
 	// Generate thumbnail URL if exists
 	var thumbnailURL string
@@ -197,16 +294,159 @@ func GetVideo(c *fiber.Ctx) error {
 		thumbnailURL, _ = services.GetVideoURL(video.Thumbnail, 1*time.Hour)
 	}
 
+	// Candidate thumbnails (for the creator's thumbnail picker)
+	candidateURLs := make([]string, 0, len(video.ThumbnailCandidates))
+	for _, candidate := range video.ThumbnailCandidates {
+		if url, err := services.GetVideoURL(candidate, 1*time.Hour); err == nil {
+			candidateURLs = append(candidateURLs, url)
+		}
+	}
+
+	// Scrub preview track is served by the API so its sprite URIs can be presigned
+	var previewVTTURL string
+	if video.PreviewVTT != "" {
+		previewVTTURL = fmt.Sprintf("/api/v1/videos/%d/previews.vtt", video.ID)
+	}
+
+	// HLS playback (playlists are served by the API so segment URIs can be presigned)
+	var hlsURL string
+	if video.Status == "ready" && video.HLSPath != "" {
+		hlsURL = playbackURL(&video)
+	}
+
 	// Increment views
 	database.DB.Model(&video).UpdateColumn("views", video.Views+1)
 
+	// Comments are paged through the comments endpoint
+	var commentCount int64
+	database.DB.Model(&models.Comment{}).Where("video_id = ?", video.ID).Count(&commentCount)
+
 	return utils.SuccessResponse(c, fiber.Map{
-		"video":         video,
-		"video_url":     videoURL,
-		"thumbnail_url": thumbnailURL,
+		"video":                    video,
+		"video_url":                videoURL,
+		"playback_url":             hlsURL,
+		"thumbnail_url":            thumbnailURL,
+		"thumbnail_candidate_urls": candidateURLs,
+		"preview_vtt_url":          previewVTTURL,
+		"comment_count":            commentCount,
+		"comments_url":             fmt.Sprintf("/api/v1/videos/%d/comments", video.ID),
 	})
 }
 
+// GetVideoPreviews serves the scrub preview WebVTT track with presigned sprite URLs - GET /api/v1/videos/:id/previews.vtt
+func GetVideoPreviews(c *fiber.Ctx) error {
+	videoID := c.Params("id")
+
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	if !canWatchVideo(c, &video) {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	if video.PreviewVTT == "" {
+		return utils.ErrorResponse(c, "Previews not available", fiber.StatusNotFound)
+	}
+
+	content, err := services.ReadObject(video.PreviewVTT)
+	if err != nil {
+		return utils.ErrorResponse(c, "Failed to load previews", fiber.StatusInternalServerError)
+	}
+
+	// Sprite URIs in the track are relative to the VTT object (e.g., sprites/sprite_000.jpg#xywh=0,0,160,90)
+	baseDir := path.Dir(video.PreviewVTT)
+	signed := make(map[string]string) // one presigned URL per sprite sheet
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		sprite, fragment, found := strings.Cut(line, "#xywh=")
+		if !found {
+			continue
+		}
+
+		url, ok := signed[sprite]
+		if !ok {
+			url, err = services.GetVideoURL(path.Join(baseDir, sprite), 1*time.Hour)
+			if err != nil {
+				return utils.ErrorResponse(c, "Failed to generate preview URLs", fiber.StatusInternalServerError)
+			}
+			signed[sprite] = url
+		}
+
+		lines[i] = url + "#xywh=" + fragment
+	}
+
+	c.Set(fiber.HeaderContentType, "text/vtt; charset=utf-8")
+	return c.SendString(strings.Join(lines, "\n"))
+}
+
+// GetVideoProcessing returns the processing progress of the owner's video - GET /api/v1/videos/:id/processing
+func GetVideoProcessing(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	videoID := c.Params("id")
+
+	var video models.Video
+	if err := database.DB.First(&video, videoID).Error; err != nil {
+		return utils.ErrorResponse(c, "Video not found", fiber.StatusNotFound)
+	}
+
+	// Check ownership
+	if video.UserID != userID {
+		return utils.ErrorResponse(c, "You don't have permission to view this video's processing", fiber.StatusForbidden)
+	}
+
+	// Latest job of the video
+	var job models.ProcessingJob
+	if err := database.DB.Where("video_id = ?", video.ID).Order("id DESC").First(&job).Error; err != nil {
+		return utils.ErrorResponse(c, "No processing job found for this video", fiber.StatusNotFound)
+	}
+
+	return utils.SuccessResponse(c, processingStatus(&video, &job))
+}
+
+// Processing state of a video as exposed to its owner
+func processingStatus(video *models.Video, job *models.ProcessingJob) fiber.Map {
+	status := fiber.Map{
+		"video_id":        video.ID,
+		"video_status":    video.Status,
+		"job_id":          job.ID,
+		"status":          job.Status,
+		"stage":           job.Stage,
+		"progress":        job.Progress,
+		"eta_seconds":     job.ETASeconds,
+		"attempts":        job.Attempts,
+		"max_attempts":    job.MaxAttempts,
+		"next_attempt_at": job.NextAttemptAt,
+		"started_at":      job.StartedAt,
+		"updated_at":      job.UpdatedAt,
+	}
+
+	// Tell the creator why the upload was rejected
+	if job.Status == "failed" || job.Status == "dead" {
+		status["error"] = lastLogLine(job.Logs)
+	}
+
+	return status
+}
+
+// Last non-empty line of the job logs (the most recent error)
+func lastLogLine(logs string) string {
+	lines := strings.Split(strings.TrimSpace(logs), "\n")
+	return lines[len(lines)-1]
+}
+
+// Presigns the poster of every video in a listing
+func attachThumbnailURLs(videos []models.Video) {
+	for i := range videos {
+		if videos[i].Thumbnail == "" {
+			continue
+		}
+		videos[i].ThumbnailURL, _ = services.GetVideoURL(videos[i].Thumbnail, 1*time.Hour)
+	}
+}
+
 // DeleteVideo deletes video from MinIO and database - DELETE /api/v1/videos/:id
 func DeleteVideo(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
@@ -222,24 +462,33 @@ func DeleteVideo(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "You don't have permission to delete this video", fiber.StatusForbidden)
 	}
 
+	// Stored files are removed by a cleanup job, committed together with the deletion
+	cleanupJob := services.NewCleanupJob(&video, userID)
+
 	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		// Delete associated comments first (child records)
 		if err := tx.Where("video_id = ?", video.ID).Delete(&models.Comment{}).Error; err != nil {
 			return err
 		}
-		// Delete associated processing jobs first (child records)
+		if err := tx.Where("video_id = ?", video.ID).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		// A worker still processing the video may upload files: the cleanup waits for its lease to end
+		if err := services.DeferCleanupForProcessing(tx, &cleanupJob); err != nil {
+			return err
+		}
+		// Delete associated processing jobs first (child records), their workers abort at the next heartbeat
 		if err := tx.Where("video_id = ?", video.ID).Delete(&models.ProcessingJob{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("video_id = ?", video.ID).Delete(&models.MediaInfo{}).Error; err != nil {
+			return err
+		}
 		if err := tx.Delete(&video).Error; err != nil {
 			return err
 		}
 
-		// if video.Status == "ready" {
-		// 	services.DeleteHLSFolder(video.ID)
-		// }
-
-		return nil
+		return tx.Create(&cleanupJob).Error
 	})
 
 	if err != nil {
@@ -248,19 +497,8 @@ func DeleteVideo(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, "Failed to delete video", fiber.StatusInternalServerError)
 	}
 
-	go func() {
-		if video.MinioKey != "" {
-			services.DeleteVideo(video.MinioKey)
-		}
-		if video.Thumbnail != "" {
-			services.DeleteVideo(video.Thumbnail)
-		}
-	}()
-
-	// Delete from database (soft delete)
-	if err := database.DB.Delete(&video).Error; err != nil {
-		return utils.ErrorResponse(c, "Failed to delete video record", fiber.StatusInternalServerError)
-	}
+	// Run the cleanup right away (jobs left behind or deferred are run by the periodic sweep)
+	go services.ProcessCleanupJobs(database.DB)
 
 	return utils.SuccessResponse(c, fiber.Map{
 		"message": "Video deleted successfully",
@@ -284,15 +522,34 @@ func UpdateVideo(c *fiber.Ctx) error {
 
 	// Parse request body
 	var updates struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Sport       string `json:"sport"`
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+		Sport       string  `json:"sport"`
+		Visibility  string  `json:"visibility"`
+		PublishAt   *string `json:"publish_at"` // "" publishes right away
 	}
 
 	if err := c.BodyParser(&updates); err != nil {
 		return utils.ErrorResponse(c, "Invalid request body", fiber.StatusBadRequest)
 	}
 
+	// Publishing settings (unchanged when omitted)
+	if updates.Visibility == "" {
+		updates.Visibility = video.Visibility
+	}
+	publishAt := ""
+	if updates.PublishAt != nil {
+		publishAt = *updates.PublishAt
+	} else if video.PublishAt != nil {
+		publishAt = video.PublishAt.Format(time.RFC3339)
+	}
+	visibility, publishTime, err := parsePublishing(updates.Visibility, publishAt)
+	if err != nil {
+		return utils.ErrorResponse(c, err.Error(), fiber.StatusBadRequest)
+	}
+	video.Visibility = visibility
+	video.PublishAt = publishTime
+
 	// Update fields
 	if updates.Title != "" {
 		video.Title = updates.Title
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/mailer"
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+const (
+	// Validity of the emailed verification links
+	EmailVerificationTTL = 24 * time.Hour
+
+	// Validity of the emailed password reset links
+	PasswordResetTTL = 1 * time.Hour
+
+	// Min delay between two emails of the same kind to the same user
+	emailResendCooldown = 1 * time.Minute
+
+	// Max time spent handing an email to the mailer
+	mailSendTimeout = 30 * time.Second
+)
+
+var (
+	ErrInvalidUserToken  = errors.New("invalid or expired token")
+	ErrEmailSentRecently = errors.New("an email was sent recently, try again in a minute")
+)
+
+// SendVerificationEmail emails the user a link verifying their address (previous links stop working)
+func SendVerificationEmail(db *gorm.DB, user *models.User) error {
+	if emailSentRecently(db, user.ID, "email_verification") {
+		return ErrEmailSentRecently
+	}
+
+	token, err := IssueUserToken(db, user.ID, "email_verification", EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := appURL() + "/api/v1/auth/verify?token=" + url.QueryEscape(token)
+	return sendEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GoSport email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %d hours. If you didn't create a GoSport account, ignore this email.\n",
+			user.Username, link, int(EmailVerificationTTL.Hours())),
+	})
+}
+
+// VerifyEmail marks the address of the token's user as verified
+func VerifyEmail(db *gorm.DB, token string) (*models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := ConsumeUserToken(tx, token, "email_verification")
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			return ErrInvalidUserToken
+		}
+		user.Verified = true
+		return tx.Model(&user).Update("verified", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ChangePassword sets a new password and revokes every session of the user
+func ChangePassword(db *gorm.DB, user *models.User, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, user.ID, "password_change")
+	})
+}
+
+// SendPasswordResetEmail emails the user a link to choose a new password (previous links stop working)
+func SendPasswordResetEmail(db *gorm.DB, user *models.User) error {
+	if emailSentRecently(db, user.ID, "password_reset") {
+		return ErrEmailSentRecently
+	}
+
+	token, err := IssueUserToken(db, user.ID, "password_reset", PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := passwordResetURL() + "?token=" + url.QueryEscape(token)
+	return sendEmail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GoSport password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your GoSport account. Choose a new one here:\n\n%s\n\n"+
+			"The link expires in %d minutes and works once. If it wasn't you, ignore this email, your password stays the same.\n",
+			user.Username, link, int(PasswordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password with an emailed reset token, and revokes every session of the user
+func ResetPassword(db *gorm.DB, token, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := ConsumeUserToken(tx, token, "password_reset")
+		if err != nil {
+			return err
+		}
+
+		// Receiving the email proves the address, too, and lifts a lockout
+		if err := tx.Model(&models.User{}).Where("id = ?", userToken.UserID).Updates(map[string]interface{}{
+			"password":      hashedPassword,
+			"verified":      true,
+			"failed_logins": 0,
+			"locked_until":  nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		return RevokeUserSessions(tx, userToken.UserID, "password_reset")
+	})
+}
+
+// IssueUserToken creates a single-use token for the user, replacing the unused ones with the same purpose
+func IssueUserToken(db *gorm.DB, userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hashSecretToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConsumeUserToken marks a token as used, it must be unused, unexpired and issued for purpose
+// (run it in the transaction applying what the token allows)
+func ConsumeUserToken(tx *gorm.DB, token, purpose string) (*models.UserToken, error) {
+	var userToken models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", hashSecretToken(token), purpose).
+		First(&userToken).Error; err != nil {
+		return nil, ErrInvalidUserToken
+	}
+
+	if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	now := time.Now()
+	if err := tx.Model(&userToken).Update("used_at", now).Error; err != nil {
+		return nil, err
+	}
+	userToken.UsedAt = &now
+
+	return &userToken, nil
+}
+
+// Whether an email with this purpose was sent to the user during the cooldown
+func emailSentRecently(db *gorm.DB, userID uint, purpose string) bool {
+	var lastSent models.UserToken
+	err := db.Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at DESC").First(&lastSent).Error
+	return err == nil && time.Since(lastSent.CreatedAt) < emailResendCooldown
+}
+
+// Whether uploads and comments require a verified email address (REQUIRE_VERIFIED_EMAIL=true)
+func VerifiedEmailRequired() bool {
+	return os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+}
+
+func sendEmail(msg mailer.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+
+	return mailer.Default.Send(ctx, msg)
+}
+
+// Public URL of the API, used in emailed links (APP_URL, e.g., https://gosport.example.com)
+func appURL() string {
+	if base := os.Getenv("APP_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return "http://localhost:" + os.Getenv("BACKEND_PORT")
+}
+
+// Page of the client where users choose their new password (PASSWORD_RESET_URL, gets ?token=)
+func passwordResetURL() string {
+	if page := os.Getenv("PASSWORD_RESET_URL"); page != "" {
+		return page
+	}
+	return appURL() + "/reset-password"
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Backoff between cleanup attempts: 1m, 2m, 4m ... capped at 1h
+const (
+	cleanupRetryBaseDelay = 1 * time.Minute
+	cleanupRetryMaxDelay  = 1 * time.Hour
+)
+
+// Time a claimed cleanup job is reserved for the instance running it
+const cleanupLeaseDuration = 10 * time.Minute
+
+// NewCleanupJob lists everything stored for a video: the original, the artwork and the worker output folders
+func NewCleanupJob(video *models.Video, requestedBy uint) models.CleanupJob {
+	var objects []string
+	for _, key := range []string{video.MinioKey, video.Thumbnail} {
+		if key != "" {
+			objects = append(objects, key)
+		}
+	}
+
+	return models.CleanupJob{
+		VideoID:     video.ID,
+		RequestedBy: requestedBy,
+		Objects:     objects,
+		Prefixes: []string{
+			fmt.Sprintf("videos/hls/%d/", video.ID),        // playlists and segments
+			fmt.Sprintf("videos/thumbnails/%d/", video.ID), // poster, candidates, preview sprites and VTT
+		},
+		Status: "queued",
+		Logs:   logEntry(fmt.Sprintf("requested by user %d for video %q", requestedBy, video.Title)),
+	}
+}
+
+// DeferCleanupForProcessing delays the cleanup job until the lease of the video's job being processed ends:
+// its worker may still upload files under the removed prefixes. Call it in the transaction deleting the
+// processing jobs (the rows are locked, so the lease read is the last one), the worker aborts at its next
+// heartbeat once its job is gone, well before the lease ends.
+func DeferCleanupForProcessing(tx *gorm.DB, job *models.CleanupJob) error {
+	var processing []models.ProcessingJob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "lease_expires_at").
+		Where("video_id = ? AND status = ?", job.VideoID, "processing").
+		Find(&processing).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, p := range processing {
+		lease := p.LeaseExpiresAt
+		if lease == nil || !lease.After(now) || (job.NextAttemptAt != nil && !lease.After(*job.NextAttemptAt)) {
+			continue
+		}
+		job.NextAttemptAt = lease
+	}
+
+	if job.NextAttemptAt != nil {
+		job.Logs += logEntry(fmt.Sprintf("video was processing, waiting for the worker's lease to end at %s",
+			job.NextAttemptAt.UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// ProcessCleanupJobs runs every due cleanup job (safe to call from several API instances)
+func ProcessCleanupJobs(db *gorm.DB) {
+	for {
+		found, err := processNextCleanupJob(db)
+		if err != nil {
+			log.Printf("Failed to process cleanup job: %v", err)
+			return
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// Claims the oldest due job and runs it. The deletes happen outside any transaction,
+// the lease keeps other instances away meanwhile.
+func processNextCleanupJob(db *gorm.DB) (bool, error) {
+	job, err := claimCleanupJob(db)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	runCleanupJob(job)
+	return true, saveCleanupResult(db, job)
+}
+
+// Marks the oldest due job (or a running one with an expired lease) as running, in a short transaction
+func claimCleanupJob(db *gorm.DB) (*models.CleanupJob, error) {
+	var job models.CleanupJob
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND lease_expires_at < ?)",
+				"queued", now, "running", now).
+			Order("id").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		// Postgres keeps microseconds, the lease is compared when saving the result
+		lease := now.Add(cleanupLeaseDuration).Truncate(time.Microsecond)
+		job.Status = "running"
+		job.LeaseExpiresAt = &lease
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":           job.Status,
+			"lease_expires_at": lease,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Records the outcome of a run, unless the lease expired and another instance took the job over meanwhile
+func saveCleanupResult(db *gorm.DB, job *models.CleanupJob) error {
+	lease := *job.LeaseExpiresAt
+	job.LeaseExpiresAt = nil
+
+	result := db.Model(&models.CleanupJob{}).
+		Where("id = ? AND status = ? AND lease_expires_at = ?", job.ID, "running", lease).
+		Updates(map[string]interface{}{
+			"status":           job.Status,
+			"attempts":         job.Attempts,
+			"next_attempt_at":  job.NextAttemptAt,
+			"deleted_objects":  job.DeletedObjects,
+			"completed_at":     job.CompletedAt,
+			"logs":             job.Logs,
+			"lease_expires_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("⚠️  Lease of cleanup job ID %d expired during the run, result dropped", job.ID)
+	}
+
+	return nil
+}
+
+// Removes the objects of the job, then schedules a retry or gives up when something is left
+func runCleanupJob(job *models.CleanupJob) {
+	job.Attempts++
+
+	var failures []error
+	removed := 0
+
+	for _, object := range job.Objects {
+		// Removing a missing object is not an error, so retries are idempotent
+		if err := DeleteVideo(object); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", object, err))
+			continue
+		}
+		removed++
+	}
+
+	for _, prefix := range job.Prefixes {
+		count, err := DeletePrefix(prefix)
+		removed += count
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+
+	job.DeletedObjects += removed
+	err := errors.Join(failures...)
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		log.Printf("🧹 Cleanup job ID %d removed the files of video ID %d", job.ID, job.VideoID)
+		job.Status = "completed"
+		job.CompletedAt = &now
+		job.NextAttemptAt = nil
+		job.Logs += logEntry(fmt.Sprintf("attempt %d/%d removed %d object(s), done", job.Attempts, job.MaxAttempts, removed))
+
+	case job.Attempts >= job.MaxAttempts:
+		log.Printf("💀 Cleanup job ID %d exhausted its %d attempts: %v", job.ID, job.MaxAttempts, err)
+		job.Status = "dead"
+		job.NextAttemptAt = nil
+		job.Logs += logEntry(fmt.Sprintf("attempt %d/%d removed %d object(s), failed: %v", job.Attempts, job.MaxAttempts, removed, err)) +
+			logEntry("giving up after max attempts")
+
+	default:
+		nextAttempt := time.Now().Add(cleanupBackoff(job.Attempts))
+		job.Status = "queued"
+		job.NextAttemptAt = &nextAttempt
+		job.Logs += logEntry(fmt.Sprintf("attempt %d/%d removed %d object(s), failed: %v", job.Attempts, job.MaxAttempts, removed, err))
+	}
+}
+
+// Delay before the next attempt, doubling with every failed attempt
+func cleanupBackoff(attempts int) time.Duration {
+	delay := cleanupRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= cleanupRetryMaxDelay {
+			return cleanupRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// Timestamped audit trail line
+func logEntry(message string) string {
+	return fmt.Sprintf("[%s] %s\n", time.Now().UTC().Format(time.RFC3339), message)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/alex6damian/GoSport/pkg/database"
+)
+
+// Delay before reconnecting a dropped LISTEN connection
+const jobEventsReconnectDelay = 5 * time.Second
+
+// Processing event streams waiting for changes of a video's jobs, woken by the worker's NOTIFYs
+var jobEvents = struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan struct{}]struct{}
+	listening   atomic.Bool
+}{subscribers: map[uint]map[chan struct{}]struct{}{}}
+
+// ListenForJobProgress LISTENs for job changes on a dedicated connection and wakes the subscribers
+// of the video, reconnecting until ctx is done
+func ListenForJobProgress(ctx context.Context, dsn string) {
+	for {
+		err := listenJobProgress(ctx, dsn)
+		jobEvents.listening.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("⚠️  Job progress listener disconnected: %v. Reconnecting in %s (streams poll meanwhile)...", err, jobEventsReconnectDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jobEventsReconnectDelay):
+		}
+	}
+}
+
+// JobProgressListening reports whether job changes are pushed (streams have to poll otherwise)
+func JobProgressListening() bool {
+	return jobEvents.listening.Load()
+}
+
+// SubscribeJobProgress returns a channel signalled when a job of the video changes, and its unsubscribe func.
+// Signals are coalesced, receivers re-read the job.
+func SubscribeJobProgress(videoID uint) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	jobEvents.mu.Lock()
+	if jobEvents.subscribers[videoID] == nil {
+		jobEvents.subscribers[videoID] = map[chan struct{}]struct{}{}
+	}
+	jobEvents.subscribers[videoID][ch] = struct{}{}
+	jobEvents.mu.Unlock()
+
+	return ch, func() {
+		jobEvents.mu.Lock()
+		delete(jobEvents.subscribers[videoID], ch)
+		if len(jobEvents.subscribers[videoID]) == 0 {
+			delete(jobEvents.subscribers, videoID)
+		}
+		jobEvents.mu.Unlock()
+	}
+}
+
+// Holds one LISTEN connection until it fails or ctx is done
+func listenJobProgress(ctx context.Context, dsn string) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{database.JobProgressChannel}.Sanitize()); err != nil {
+		return err
+	}
+	log.Printf("👂 Listening for job progress on channel %q", database.JobProgressChannel)
+	jobEvents.listening.Store(true)
+
+	// Changes may have been missed while we were not listening
+	publishAllJobProgress()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		videoID, err := strconv.ParseUint(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+		publishJobProgress(uint(videoID))
+	}
+}
+
+func publishJobProgress(videoID uint) {
+	jobEvents.mu.Lock()
+	defer jobEvents.mu.Unlock()
+
+	for ch := range jobEvents.subscribers[videoID] {
+		signalSubscriber(ch)
+	}
+}
+
+func publishAllJobProgress() {
+	jobEvents.mu.Lock()
+	defer jobEvents.mu.Unlock()
+
+	for _, subscribers := range jobEvents.subscribers {
+		for ch := range subscribers {
+			signalSubscriber(ch)
+		}
+	}
+}
+
+// Non-blocking send: a pending signal already makes the receiver re-read the job
+func signalSubscriber(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
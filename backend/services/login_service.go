@@ -0,0 +1,160 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Progressive lockout: 5 failed logins in a row lock the account for 1m, every further failure doubles it (capped at 1h)
+const (
+	lockoutThreshold = 5
+	lockoutBaseDelay = 1 * time.Minute
+	lockoutMaxDelay  = 1 * time.Hour
+
+	// Failures older than this are forgotten: the count starts over at the next one
+	failedLoginDecay = 24 * time.Hour
+)
+
+// Login events are kept 90 days
+const loginEventRetention = 90 * 24 * time.Hour
+
+// Hash compared against when there is no password to check, so every refused login costs the same bcrypt time
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("gosport-dummy-password")
+	if err != nil {
+		log.Printf("Failed to hash the dummy password: %v", err)
+	}
+	return hash
+})
+
+// CheckDummyPassword spends the time of a password check for unknown emails and locked accounts,
+// so the response time doesn't tell whether an account exists or is locked
+func CheckDummyPassword(password string) {
+	utils.CheckPassword(dummyPasswordHash(), password)
+}
+
+// LockedUntil returns the end of the user's lockout, nil when they may log in
+func LockedUntil(user *models.User) *time.Time {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return user.LockedUntil
+	}
+	return nil
+}
+
+// RecordFailedLogin records a failed attempt (user is nil for unknown emails) and, past the threshold,
+// locks the account
+func RecordFailedLogin(db *gorm.DB, user *models.User, email, ip, userAgent, reason string) {
+	event := models.LoginEvent{
+		Email:     email,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if user != nil {
+			event.UserID = &user.ID
+
+			// Attempts already refused by the lockout don't extend it
+			if reason != "locked" {
+				if err := countFailedLogin(tx, user.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		log.Printf("Failed to record failed login for %s: %v", email, err)
+	}
+}
+
+// RecordLogin records a successful login and clears the failed attempts of the user
+func RecordLogin(db *gorm.DB, user *models.User, sessionID, ip, userAgent string) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"failed_logins":        0,
+			"locked_until":         nil,
+			"last_failed_login_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.LoginEvent{
+			UserID:    &user.ID,
+			Email:     user.Email,
+			IP:        ip,
+			UserAgent: userAgent,
+			Success:   true,
+			SessionID: sessionID,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to record login of user ID %d: %v", user.ID, err)
+	}
+}
+
+// PurgeLoginEvents deletes the login events older than the retention period
+func PurgeLoginEvents(db *gorm.DB) {
+	result := db.Where("created_at < ?", time.Now().Add(-loginEventRetention)).Delete(&models.LoginEvent{})
+	if result.Error != nil {
+		log.Printf("Failed to purge login events: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d old login event(s)", result.RowsAffected)
+	}
+}
+
+// Increments the failed logins of the user (row locked, so parallel attempts all count)
+func countFailedLogin(tx *gorm.DB, userID uint) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "failed_logins", "locked_until", "last_failed_login_at").
+		First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.FailedLogins = failedLoginsAfter(user.FailedLogins, user.LastFailedLoginAt, now)
+	if user.FailedLogins >= lockoutThreshold {
+		until := now.Add(lockoutDelay(user.FailedLogins))
+		user.LockedUntil = &until
+		log.Printf("🔒 User ID %d locked until %s after %d failed logins", userID, until.Format(time.RFC3339), user.FailedLogins)
+	}
+
+	return tx.Model(&user).Updates(map[string]interface{}{
+		"failed_logins":        user.FailedLogins,
+		"locked_until":         user.LockedUntil,
+		"last_failed_login_at": now,
+	}).Error
+}
+
+// Failed logins counted after a new failure: the previous ones decay after a quiet period
+func failedLoginsAfter(failures int, lastFailure *time.Time, now time.Time) int {
+	if lastFailure != nil && now.Sub(*lastFailure) >= failedLoginDecay {
+		return 1
+	}
+	return failures + 1
+}
+
+// Lockout length after the given number of consecutive failures, doubling past the threshold
+func lockoutDelay(failures int) time.Duration {
+	delay := lockoutBaseDelay
+	for i := lockoutThreshold; i < failures; i++ {
+		delay *= 2
+		if delay >= lockoutMaxDelay {
+			return lockoutMaxDelay
+		}
+	}
+	return delay
+}
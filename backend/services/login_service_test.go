@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{5, 1 * time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{8, 8 * time.Minute},
+		{9, 16 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, 1 * time.Hour}, // 64m, capped
+		{12, 1 * time.Hour},
+		{100, 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := lockoutDelay(tt.failures); got != tt.want {
+			t.Errorf("lockoutDelay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestFailedLoginsAfter(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name        string
+		failures    int
+		lastFailure *time.Time
+		want        int
+	}{
+		{"first failure", 0, nil, 1},
+		{"recent failures add up", 3, ago(time.Minute), 4},
+		{"still counting just before the decay", 7, ago(failedLoginDecay - time.Second), 8},
+		{"quiet period resets the count", 7, ago(failedLoginDecay), 1},
+		{"long quiet period", 12, ago(30 * 24 * time.Hour), 1},
+		{"no previous failure time", 4, nil, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedLoginsAfter(tt.failures, tt.lastFailure, now); got != tt.want {
+				t.Errorf("failedLoginsAfter(%d) = %d, want %d", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
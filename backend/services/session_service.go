@@ -0,0 +1,291 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/alex6damian/GoSport/backend/utils"
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+const (
+	// Refresh tokens (and their session) expire after 30 days without use
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	// Ended sessions are kept a while, so users can review them
+	sessionRetention = 30 * 24 * time.Hour
+
+	// The refresh token just rotated still gets the new pair for a few seconds
+	// (concurrent refreshes from several tabs, retried request whose response was lost)
+	refreshGracePeriod = 10 * time.Second
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used, session revoked")
+	ErrSessionRevoked      = errors.New("session revoked or expired")
+)
+
+// Tokens handed to the client at login and on every refresh
+type SessionTokens struct {
+	SessionID    string    `json:"session_id"`
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // access token expiry
+}
+
+// StartSession opens a login session for the user and issues its first tokens
+func StartSession(db *gorm.DB, user *models.User, ip, userAgent string) (*SessionTokens, error) {
+	refreshToken, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	rotationKey, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:               uuid.New().String(),
+		UserID:           user.ID,
+		RefreshTokenHash: hashSecretToken(refreshToken),
+		RotationKey:      rotationKey,
+		IP:               ip,
+		UserAgent:        userAgent,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	return issueTokens(user, &session, refreshToken)
+}
+
+// RefreshSession trades a refresh token for new tokens, the old refresh token stops working.
+// Presenting the token just rotated again within refreshGracePeriod returns the current refresh token,
+// past that, presenting an already rotated token revokes the session (someone else holds a copy).
+func RefreshSession(db *gorm.DB, refreshToken, ip, userAgent string) (*SessionTokens, error) {
+	hash := hashSecretToken(refreshToken)
+
+	var tokens *SessionTokens
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", hash).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tokens, err = reissueRotatedTokens(tx, hash, refreshToken)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		var user models.User
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		updates := map[string]interface{}{}
+
+		// Sessions opened before rotation keys existed get one now
+		if session.RotationKey == "" {
+			if session.RotationKey, err = newSecretToken(); err != nil {
+				return err
+			}
+			updates["rotation_key"] = session.RotationKey
+		}
+
+		newToken := nextRefreshToken(session.RotationKey, refreshToken)
+
+		now := time.Now()
+		updates["refresh_token_hash"] = hashSecretToken(newToken)
+		updates["previous_token_hash"] = hash
+		updates["ip"] = ip
+		updates["user_agent"] = userAgent
+		updates["last_used_at"] = now
+		updates["expires_at"] = now.Add(RefreshTokenTTL)
+		if err := tx.Model(&session).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		tokens, err = issueTokens(&user, &session, newToken)
+		return err
+	})
+
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil, revokeReusedSession(db, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeSession ends a single session (e.g., logout)
+func RevokeSession(db *gorm.DB, sessionID, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RevokeUserSessions ends every session of a user (e.g., logout everywhere, password change)
+func RevokeUserSessions(db *gorm.DB, userID uint, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// CheckSession verifies that the session of an access token is still open and that
+// the role the token carries is still the user's (tokens issued before a role change are rejected)
+func CheckSession(db *gorm.DB, claims *utils.Claims) error {
+	if claims.SessionID == "" {
+		return ErrSessionRevoked
+	}
+
+	var session struct {
+		UserID    uint
+		Role      string
+		ExpiresAt time.Time
+		RevokedAt *time.Time
+	}
+	err := db.Table("sessions").
+		Select("sessions.user_id, sessions.expires_at, sessions.revoked_at, users.role").
+		Joins("JOIN users ON users.id = sessions.user_id AND users.deleted_at IS NULL").
+		Where("sessions.id = ?", claims.SessionID).
+		Take(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) ||
+		session.UserID != claims.UserID || session.Role != claims.Role {
+		return ErrSessionRevoked
+	}
+
+	return nil
+}
+
+// PurgeExpiredSessions deletes the sessions expired or revoked for over the retention period
+func PurgeExpiredSessions(db *gorm.DB) {
+	cutoff := time.Now().Add(-sessionRetention)
+	result := db.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{})
+	if result.Error != nil {
+		log.Printf("Failed to purge expired sessions: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d expired session(s)", result.RowsAffected)
+	}
+}
+
+// Issues the current tokens again to the refresh token just rotated, within the grace period.
+// Anything else (older token, grace period over) is an invalid token, reported as reuse by the caller.
+func reissueRotatedTokens(tx *gorm.DB, hash, refreshToken string) (*SessionTokens, error) {
+	var session models.Session
+	err := tx.Where("previous_token_hash = ? AND revoked_at IS NULL", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	currentToken, ok := gracePeriodToken(&session, refreshToken, time.Now())
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := tx.First(&user, session.UserID).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return issueTokens(&user, &session, currentToken)
+}
+
+// Current refresh token of the session, when refreshToken is the one it was rotated from within the grace period
+func gracePeriodToken(session *models.Session, refreshToken string, now time.Time) (string, bool) {
+	if session.RotationKey == "" || now.After(session.ExpiresAt) || now.Sub(session.LastUsedAt) > refreshGracePeriod {
+		return "", false
+	}
+
+	// Only the token it was rotated from derives the current one, not an older one
+	currentToken := nextRefreshToken(session.RotationKey, refreshToken)
+	if hashSecretToken(currentToken) != session.RefreshTokenHash {
+		return "", false
+	}
+
+	return currentToken, true
+}
+
+// Revokes the open session a rotated refresh token belonged to, if any
+func revokeReusedSession(db *gorm.DB, hash string) error {
+	var session models.Session
+	if err := db.Where("previous_token_hash = ? AND revoked_at IS NULL", hash).First(&session).Error; err != nil {
+		return ErrInvalidRefreshToken
+	}
+
+	log.Printf("🚨 Refresh token reused for session %s of user ID %d, revoking it", session.ID, session.UserID)
+	if err := RevokeSession(db, session.ID, "refresh_token_reuse"); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func issueTokens(user *models.User, session *models.Session, refreshToken string) (*SessionTokens, error) {
+	accessToken, err := utils.GenerateToken(user.ID, user.Email, user.Role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:    session.ID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(utils.AccessTokenTTL),
+	}, nil
+}
+
+// Random opaque token (256 bits), for refresh tokens and emailed tokens
+func newSecretToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Refresh token replacing refreshToken, derived so it can be handed out again during the grace period
+// (only hashes are stored). Unpredictable without the session's rotation key.
+func nextRefreshToken(rotationKey, refreshToken string) string {
+	mac := hmac.New(sha256.New, []byte(rotationKey))
+	mac.Write([]byte(refreshToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+func TestNextRefreshToken(t *testing.T) {
+	token := nextRefreshToken("key", "refresh-token")
+
+	if token != nextRefreshToken("key", "refresh-token") {
+		t.Error("nextRefreshToken() is not deterministic")
+	}
+	if token == nextRefreshToken("other-key", "refresh-token") {
+		t.Error("nextRefreshToken() does not depend on the rotation key")
+	}
+	if token == nextRefreshToken("key", "other-token") {
+		t.Error("nextRefreshToken() does not depend on the previous token")
+	}
+	if len(token) != 43 {
+		t.Errorf("nextRefreshToken() length = %d, want 43 (256 bits, base64url)", len(token))
+	}
+}
+
+func TestGracePeriodToken(t *testing.T) {
+	now := time.Now()
+	rotated := "rotated-token"
+	current := nextRefreshToken("key", rotated)
+
+	session := func(modify func(s *models.Session)) *models.Session {
+		s := &models.Session{
+			RefreshTokenHash:  hashSecretToken(current),
+			PreviousTokenHash: hashSecretToken(rotated),
+			RotationKey:       "key",
+			LastUsedAt:        now.Add(-2 * time.Second),
+			ExpiresAt:         now.Add(RefreshTokenTTL),
+		}
+		if modify != nil {
+			modify(s)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		session *models.Session
+		token   string
+		wantOK  bool
+	}{
+		{"just rotated", session(nil), rotated, true},
+		{"end of the grace period", session(func(s *models.Session) { s.LastUsedAt = now.Add(-refreshGracePeriod) }), rotated, true},
+		{"grace period over", session(func(s *models.Session) { s.LastUsedAt = now.Add(-refreshGracePeriod - time.Second) }), rotated, false},
+		{"rotated again since", session(func(s *models.Session) {
+			s.RefreshTokenHash = hashSecretToken(nextRefreshToken("key", current))
+		}), rotated, false},
+		{"other token", session(nil), "older-token", false},
+		{"other rotation key", session(func(s *models.Session) { s.RotationKey = "other-key" }), rotated, false},
+		{"session without rotation key", session(func(s *models.Session) { s.RotationKey = "" }), rotated, false},
+		{"session expired", session(func(s *models.Session) { s.ExpiresAt = now.Add(-time.Second) }), rotated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := gracePeriodToken(tt.session, tt.token, now)
+			if ok != tt.wantOK {
+				t.Fatalf("gracePeriodToken() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != current {
+				t.Errorf("gracePeriodToken() = %q, want the current refresh token %q", got, current)
+			}
+		})
+	}
+}
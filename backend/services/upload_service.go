@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"github.com/alex6damian/GoSport/pkg/storage"
+)
+
+// Uploads "completing" for longer than this were interrupted (assembling the parts takes seconds to minutes)
+const completingTimeout = 30 * time.Minute
+
+// Multipart uploads of the storage driver (both drivers support them)
+func multipart() (storage.Multipart, error) {
+	uploader, ok := storage.Default.(storage.Multipart)
+	if !ok {
+		return nil, storage.ErrNotSupported
+	}
+	return uploader, nil
+}
+
+// StartMultipartUpload creates a multipart upload and returns its ID
+func StartMultipartUpload(objectName, contentType string) (string, error) {
+	uploader, err := multipart()
+	if err != nil {
+		return "", err
+	}
+
+	return uploader.StartMultipart(context.Background(), objectName, contentType)
+}
+
+// UploadPart stores one part of a multipart upload and returns its ETag
+func UploadPart(objectName, uploadID string, partNumber int, data []byte) (string, error) {
+	uploader, err := multipart()
+	if err != nil {
+		return "", err
+	}
+
+	return uploader.PutPart(context.Background(), objectName, uploadID, partNumber,
+		bytes.NewReader(data), int64(len(data)))
+}
+
+// CompleteMultipartUpload assembles the parts into the final object
+func CompleteMultipartUpload(objectName, uploadID string, parts []models.UploadPart) error {
+	uploader, err := multipart()
+	if err != nil {
+		return err
+	}
+
+	storageParts := make([]storage.Part, 0, len(parts))
+	for _, part := range parts {
+		storageParts = append(storageParts, storage.Part{
+			Number: part.PartNumber,
+			ETag:   part.ETag,
+		})
+	}
+
+	return uploader.CompleteMultipart(context.Background(), objectName, uploadID, storageParts)
+}
+
+// AbortMultipartUpload discards a multipart upload and the parts stored so far
+func AbortMultipartUpload(objectName, uploadID string) error {
+	uploader, err := multipart()
+	if err != nil {
+		return err
+	}
+
+	return uploader.AbortMultipart(context.Background(), objectName, uploadID)
+}
+
+// PresignedUploadPolicy returns the URL and form fields of a browser POST upload straight to storage.
+// The policy only accepts objectName with exactly this content type and size (storage.ErrNotSupported on local storage).
+func PresignedUploadPolicy(objectName, contentType string, size int64, expires time.Duration) (string, map[string]string, error) {
+	presigner, ok := storage.Default.(storage.PostPolicyPresigner)
+	if !ok {
+		return "", nil, storage.ErrNotSupported
+	}
+
+	return presigner.PresignPost(context.Background(), objectName, contentType, size, expires)
+}
+
+// AbortExpiredUploads aborts active uploads past their expiry (frees the stored parts in MinIO),
+// and settles the uploads stuck completing
+func AbortExpiredUploads(db *gorm.DB) {
+	var uploads []models.Upload
+	if err := db.Where("status = ? AND expires_at < ?", "active", time.Now()).Find(&uploads).Error; err != nil {
+		log.Printf("Failed to fetch expired uploads: %v", err)
+		return
+	}
+
+	for _, upload := range uploads {
+		var err error
+		if upload.Method == "presigned" {
+			// The client may have uploaded the object without finalizing it
+			err = DeleteVideo(upload.MinioKey)
+		} else {
+			err = AbortMultipartUpload(upload.MinioKey, upload.MinioUploadID)
+		}
+		if err != nil {
+			log.Printf("Failed to abort expired upload %s: %v", upload.ID, err)
+			continue
+		}
+
+		db.Model(&upload).Update("status", "aborted")
+		db.Where("upload_id = ?", upload.ID).Delete(&models.UploadPart{})
+	}
+
+	if len(uploads) > 0 {
+		log.Printf("Aborted %d expired upload(s)", len(uploads))
+	}
+
+	settleStalledCompletions(db)
+}
+
+// Settles the uploads left "completing" by an interrupted CompleteUpload (crash or restart):
+// completed when their video was created, aborted otherwise (stored parts and assembled object removed)
+func settleStalledCompletions(db *gorm.DB) {
+	var uploads []models.Upload
+	if err := db.Where("status = ? AND updated_at < ?", "completing", time.Now().Add(-completingTimeout)).
+		Find(&uploads).Error; err != nil {
+		log.Printf("Failed to fetch stalled uploads: %v", err)
+		return
+	}
+
+	for _, upload := range uploads {
+		var video models.Video
+		err := db.Unscoped().Select("id").Where("minio_key = ?", upload.MinioKey).First(&video).Error
+		if err == nil {
+			db.Model(&upload).Where("status = ?", "completing").Updates(map[string]interface{}{
+				"status":   "completed",
+				"video_id": video.ID,
+			})
+			db.Where("upload_id = ?", upload.ID).Delete(&models.UploadPart{})
+			log.Printf("Upload %s was interrupted after creating video ID %d, marked completed", upload.ID, video.ID)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to check the video of upload %s: %v", upload.ID, err)
+			continue
+		}
+
+		// Interrupted before or after assembling the parts: drop both
+		if upload.Method == "chunked" {
+			if err := AbortMultipartUpload(upload.MinioKey, upload.MinioUploadID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Failed to abort stalled upload %s: %v", upload.ID, err)
+				continue
+			}
+		}
+		if err := DeleteVideo(upload.MinioKey); err != nil {
+			log.Printf("Failed to delete the object of stalled upload %s: %v", upload.ID, err)
+			continue
+		}
+
+		db.Model(&upload).Where("status = ?", "completing").Update("status", "aborted")
+		db.Where("upload_id = ?", upload.ID).Delete(&models.UploadPart{})
+		log.Printf("Aborted upload %s, stuck completing since %s", upload.ID, upload.UpdatedAt.Format(time.RFC3339))
+	}
+}
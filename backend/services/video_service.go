@@ -5,29 +5,22 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/alex6damian/GoSport/pkg/config"
+	"github.com/alex6damian/GoSport/pkg/storage"
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
 )
 
-// UploadVideo uploads a video file to MinIO
+// UploadVideo uploads a video file to storage
 func UploadVideo(file io.Reader, filename string, fileSize int64,
 	contentType string) (string, error) {
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
-
 	// Generate unique filename
-	ext := filepath.Ext(filename)
-	objectName := fmt.Sprintf("videos/%s%s", uuid.New().String(), ext)
+	objectName := NewVideoObjectName(filename)
 
 	// Upload file
-	_, err := config.MinioClient.PutObject(context.Background(), bucketName, objectName,
-		file, fileSize, minio.PutObjectOptions{
-			ContentType: contentType,
-		})
+	err := storage.Default.Put(context.Background(), objectName, file, fileSize, contentType)
 	if err != nil {
 		return "", err
 	}
@@ -35,72 +28,86 @@ func UploadVideo(file io.Reader, filename string, fileSize int64,
 	return objectName, nil
 }
 
+// NewVideoObjectName generates a unique object key for an original upload (videos/uuid.ext)
+func NewVideoObjectName(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return fmt.Sprintf("videos/%s%s", uuid.New().String(), ext)
+}
+
 // GetVideoURL generates a presigned URL for video access
 func GetVideoURL(objectName string, expires time.Duration) (string, error) {
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
+	return storage.Default.PresignGet(context.Background(), objectName, expires)
+}
 
-	url, err := config.MinioClient.PresignedGetObject(context.Background(),
-		bucketName, objectName, expires, nil)
+// ReadObject downloads a (small) object into memory
+func ReadObject(objectName string) ([]byte, error) {
+	object, err := storage.Default.Get(context.Background(), objectName)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer object.Close()
 
-	return url.String(), nil
+	return io.ReadAll(object)
 }
 
-// DeleteVideo removes a video from MinIO
-func DeleteVideo(objectName string) error {
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
+// ReadObjectHead downloads the first n bytes of an object (less if the object is smaller)
+func ReadObjectHead(objectName string, n int64) ([]byte, error) {
+	object, err := storage.Default.GetRange(context.Background(), objectName, 0, n-1)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return io.ReadAll(object)
+}
 
-	return config.MinioClient.RemoveObject(context.Background(), bucketName,
-		objectName, minio.RemoveObjectOptions{})
+// GetObjectRange streams bytes start..end (inclusive) of an object, the whole object when end < 0
+func GetObjectRange(objectName string, start, end int64) (io.ReadCloser, error) {
+	if end < 0 {
+		return storage.Default.Get(context.Background(), objectName)
+	}
+	return storage.Default.GetRange(context.Background(), objectName, start, end)
+}
+
+// DeleteVideo removes a video from storage
+func DeleteVideo(objectName string) error {
+	return storage.Default.Delete(context.Background(), objectName)
 }
 
 // GetVideoInfo gets object metadata
-func GetVideoInfo(objectName string) (*minio.ObjectInfo, error) {
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
+func GetVideoInfo(objectName string) (*storage.ObjectInfo, error) {
+	return storage.Default.Stat(context.Background(), objectName)
+}
 
-	info, err := config.MinioClient.StatObject(context.Background(),
-		bucketName, objectName, minio.StatObjectOptions{})
+// DeletePrefix removes every object under a prefix (e.g., "videos/hls/123/") and returns how many were removed
+func DeletePrefix(prefix string) (int, error) {
+	objects, err := storage.Default.List(context.Background(), prefix)
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("failed to list %s: %w", prefix, err)
 	}
 
-	return &info, nil
-}
-
-func DeleteHLSFolder(videoID uint) error {
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
-
-	// Construct the object prefix, which represents the folder path in MinIO.
-	// Example: "videos/hls/123/"
-	prefix := fmt.Sprintf("videos/hls/%d/", videoID)
-
-	// List all objects in the bucket that start with this prefix.
-	// The 'Recursive: true' option ensures it finds all files in the "folder".
-	objectsCh := config.MinioClient.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{
-		Prefix:    prefix,
-		Recursive: true,
-	})
-
-	// Use MinIO's efficient RemoveObjects API for bulk deletion.
-	errorCh := config.MinioClient.RemoveObjects(context.Background(), bucketName, objectsCh, minio.RemoveObjectsOptions{
-		GovernanceBypass: true,
-	})
-
-	// Check for any errors during the bulk deletion process.
-	hasErrors := false
-	for e := range errorCh {
-		if e.Err != nil {
-			hasErrors = true
-			log.Printf("Error deleting object %s: %v", e.ObjectName, e.Err)
+	removed := 0
+	for _, object := range objects {
+		if err := storage.Default.Delete(context.Background(), object.Key); err != nil {
+			log.Printf("Error deleting object %s: %v", object.Key, err)
+			continue
 		}
+		removed++
 	}
 
-	if hasErrors {
-		return fmt.Errorf("one or more objects could not be deleted from HLS folder %s", prefix)
+	if failed := len(objects) - removed; failed > 0 {
+		return removed, fmt.Errorf("%d object(s) could not be deleted from %s", failed, prefix)
 	}
 
-	log.Printf("Successfully initiated deletion for HLS folder: %s", prefix)
-	return nil
+	return removed, nil
+}
+
+// ListObjects lists every object under a prefix
+func ListObjects(prefix string) ([]storage.ObjectInfo, error) {
+	return storage.Default.List(context.Background(), prefix)
+}
+
+// WalkObjects calls fn for the objects under a prefix stored after startAfter, in key order, until fn returns false
+func WalkObjects(prefix, startAfter string, fn func(storage.ObjectInfo) bool) error {
+	return storage.Default.Walk(context.Background(), prefix, startAfter, fn)
 }
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Key signing or verifying access tokens, identified by the kid header of the tokens
+type jwtKey struct {
+	kid    string
+	method jwt.SigningMethod
+	sign   interface{} // nil for verification-only keys
+	verify interface{}
+}
+
+// Public key in the JWK format (RFC 7517), RSA or Ed25519
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// Keys verifying access tokens, served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	jwtKeys       = map[string]*jwtKey{}
+	jwtSigningKey *jwtKey
+	jwks          = JSONWebKeySet{Keys: []JSONWebKey{}}
+)
+
+// InitJWTKeys loads the keys of the access tokens.
+//
+// JWT_KEYS_DIR holds one PEM key per file, named <kid>.pem: RSA (RS256) or Ed25519 (EdDSA) private keys,
+// or public keys only verifying tokens. JWT_SIGNING_KEY is the kid signing new tokens (optional with a
+// single private key). To rotate, add the new key, sign with it, and drop the old one once its tokens expired.
+// An empty directory gets a new Ed25519 key. Without JWT_KEYS_DIR, tokens are signed with JWT_SECRET (HS256).
+func InitJWTKeys() error {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return errors.New("set JWT_KEYS_DIR (or JWT_SECRET)")
+		}
+
+		log.Println("⚠️  JWT_KEYS_DIR not set, signing access tokens with JWT_SECRET (HS256, no JWKS)")
+		jwtSigningKey = &jwtKey{method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+		jwtKeys = map[string]*jwtKey{"": jwtSigningKey}
+		return nil
+	}
+
+	keys, err := loadJWTKeys(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		key, err := generateJWTKey(dir)
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+	}
+
+	signing, err := pickSigningKey(keys, os.Getenv("JWT_SIGNING_KEY"))
+	if err != nil {
+		return err
+	}
+
+	jwtKeys = keys
+	jwtSigningKey = signing
+	jwks = buildJWKS(keys)
+
+	log.Printf("✅ JWT keys loaded (%d), signing with %s (%s)", len(keys), signing.kid, signing.method.Alg())
+	return nil
+}
+
+// JWKS returns the public keys verifying access tokens
+func JWKS() JSONWebKeySet {
+	return jwks
+}
+
+// Reads the <kid>.pem keys of the directory
+func loadJWTKeys(dir string) (map[string]*jwtKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*jwtKey, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := parseJWTKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %s: %w", file, err)
+		}
+		keys[kid] = key
+	}
+
+	return keys, nil
+}
+
+func parseJWTKey(kid string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.sign, key.verify = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.verify = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.sign, key.verify = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.verify = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (allowed: RSA, Ed25519)", parsed)
+	}
+
+	if rsaKey, ok := key.verify.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys need at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// Creates an Ed25519 key in the directory, named after the current date
+func generateJWTKey(dir string) (*jwtKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	kid := "ed25519-" + time.Now().UTC().Format("20060102-150405")
+	file := filepath.Join(dir, kid+".pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔑 Generated JWT signing key %s", file)
+	return &jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, sign: private, verify: public}, nil
+}
+
+// The JWT_SIGNING_KEY key, or the only private key
+func pickSigningKey(keys map[string]*jwtKey, kid string) (*jwtKey, error) {
+	if kid != "" {
+		key, ok := keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY %q not found in JWT_KEYS_DIR", kid)
+		}
+		if key.sign == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY %q is a public key", kid)
+		}
+		return key, nil
+	}
+
+	var signing *jwtKey
+	for _, key := range keys {
+		if key.sign == nil {
+			continue
+		}
+		if signing != nil {
+			return nil, errors.New("several private keys in JWT_KEYS_DIR, set JWT_SIGNING_KEY")
+		}
+		signing = key
+	}
+	if signing == nil {
+		return nil, errors.New("no private key in JWT_KEYS_DIR")
+	}
+
+	return signing, nil
+}
+
+// Public keys in the JWK format, sorted by kid
+func buildJWKS(keys map[string]*jwtKey) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JSONWebKey{Use: "sig", Alg: key.method.Alg(), Kid: key.kid}
+
+		switch public := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
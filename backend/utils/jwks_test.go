@@ -0,0 +1,368 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Generated once, RSA key generation is slow
+var (
+	testRSAKey     = mustRSAKey(2048)
+	testEd25519Key = mustEd25519Key()
+)
+
+func mustRSAKey(bits int) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustEd25519Key() ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func pemBlock(t *testing.T, blockType string, der []byte, err error) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func pkcs8PEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	return pemBlock(t, "PRIVATE KEY", der, err)
+}
+
+func pkixPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	return pemBlock(t, "PUBLIC KEY", der, err)
+}
+
+// Restores the package keys once the test is done
+func keepJWTKeys(t *testing.T) {
+	keys, signing, set := jwtKeys, jwtSigningKey, jwks
+	t.Cleanup(func() {
+		jwtKeys, jwtSigningKey, jwks = keys, signing, set
+	})
+}
+
+// Loads a key directory holding the given <kid>.pem files
+func initKeysDir(t *testing.T, files map[string][]byte, signingKid string) error {
+	t.Helper()
+	keepJWTKeys(t)
+
+	dir := t.TempDir()
+	for kid, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("JWT_KEYS_DIR", dir)
+	t.Setenv("JWT_SIGNING_KEY", signingKid)
+	return InitJWTKeys()
+}
+
+// Signs claims that would pass validation, with any method, kid and key
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+
+	claims := Claims{
+		UserID: 1,
+		Email:  "user@example.com",
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    tokenIssuer,
+		},
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseJWTKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantAlg    string
+		wantSigner bool
+		wantErr    string
+	}{
+		{"RSA PKCS1 private key", pemBlock(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey), nil), "RS256", true, ""},
+		{"RSA PKCS8 private key", pkcs8PEM(t, testRSAKey), "RS256", true, ""},
+		{"RSA public key", pkixPEM(t, &testRSAKey.PublicKey), "RS256", false, ""},
+		{"Ed25519 PKCS8 private key", pkcs8PEM(t, testEd25519Key), "EdDSA", true, ""},
+		{"Ed25519 public key", pkixPEM(t, testEd25519Key.Public()), "EdDSA", false, ""},
+		{"RSA key under 2048 bits", pkcs8PEM(t, mustRSAKey(1024)), "", false, "at least 2048 bits"},
+		{"ECDSA key", pkcs8PEM(t, ecKey), "", false, "unsupported key type"},
+		{"certificate", pemBlock(t, "CERTIFICATE", []byte{0x30}, nil), "", false, "unsupported PEM block"},
+		{"not PEM", []byte("not a key"), "", false, "no PEM block"},
+		{"corrupted DER", pemBlock(t, "PRIVATE KEY", []byte{0x30, 0x03, 0x02, 0x01}, nil), "", false, "asn1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseJWTKey("kid", tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseJWTKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJWTKey() error = %v", err)
+			}
+
+			if key.kid != "kid" || key.method.Alg() != tt.wantAlg {
+				t.Errorf("parseJWTKey() = kid %q, alg %s, want kid %q, alg %s", key.kid, key.method.Alg(), "kid", tt.wantAlg)
+			}
+			if (key.sign != nil) != tt.wantSigner {
+				t.Errorf("parseJWTKey() signs = %v, want %v", key.sign != nil, tt.wantSigner)
+			}
+			if key.verify == nil {
+				t.Error("parseJWTKey() has no verification key")
+			}
+		})
+	}
+}
+
+func TestPickSigningKey(t *testing.T) {
+	rsaKey := &jwtKey{kid: "rsa", method: jwt.SigningMethodRS256, sign: testRSAKey, verify: &testRSAKey.PublicKey}
+	edKey := &jwtKey{kid: "ed", method: jwt.SigningMethodEdDSA, sign: testEd25519Key, verify: testEd25519Key.Public()}
+	publicKey := &jwtKey{kid: "public", method: jwt.SigningMethodRS256, verify: &testRSAKey.PublicKey}
+
+	tests := []struct {
+		name    string
+		keys    []*jwtKey
+		kid     string
+		want    string
+		wantErr string
+	}{
+		{"single private key", []*jwtKey{rsaKey, publicKey}, "", "rsa", ""},
+		{"several private keys without JWT_SIGNING_KEY", []*jwtKey{rsaKey, edKey}, "", "", "several private keys"},
+		{"several private keys with JWT_SIGNING_KEY", []*jwtKey{rsaKey, edKey, publicKey}, "ed", "ed", ""},
+		{"unknown JWT_SIGNING_KEY", []*jwtKey{rsaKey}, "missing", "", "not found"},
+		{"JWT_SIGNING_KEY is a public key", []*jwtKey{rsaKey, publicKey}, "public", "", "is a public key"},
+		{"public keys only", []*jwtKey{publicKey}, "", "", "no private key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := map[string]*jwtKey{}
+			for _, key := range tt.keys {
+				keys[key.kid] = key
+			}
+
+			key, err := pickSigningKey(keys, tt.kid)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("pickSigningKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pickSigningKey() error = %v", err)
+			}
+			if key.kid != tt.want {
+				t.Errorf("pickSigningKey() = %q, want %q", key.kid, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitJWTKeysSeveralPrivateKeys(t *testing.T) {
+	files := map[string][]byte{"rsa": pkcs8PEM(t, testRSAKey), "ed": pkcs8PEM(t, testEd25519Key)}
+
+	if err := initKeysDir(t, files, ""); err == nil || !strings.Contains(err.Error(), "set JWT_SIGNING_KEY") {
+		t.Fatalf("InitJWTKeys() error = %v, want JWT_SIGNING_KEY required", err)
+	}
+	if err := initKeysDir(t, files, "ed"); err != nil {
+		t.Fatalf("InitJWTKeys() with JWT_SIGNING_KEY error = %v", err)
+	}
+}
+
+func TestInitJWTKeysGeneratesKey(t *testing.T) {
+	if err := initKeysDir(t, nil, ""); err != nil {
+		t.Fatalf("InitJWTKeys() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(os.Getenv("JWT_KEYS_DIR"), "*.pem"))
+	if len(files) != 1 {
+		t.Fatalf("got %d key files, want 1 generated key", len(files))
+	}
+	if jwtSigningKey.method.Alg() != "EdDSA" || !strings.HasPrefix(jwtSigningKey.kid, "ed25519-") {
+		t.Errorf("generated key = kid %q, alg %s, want an ed25519-* EdDSA key", jwtSigningKey.kid, jwtSigningKey.method.Alg())
+	}
+}
+
+func TestValidateTokenKeysDir(t *testing.T) {
+	files := map[string][]byte{
+		"rsa": pkcs8PEM(t, testRSAKey),
+		"ed":  pkcs8PEM(t, testEd25519Key),
+	}
+	if err := initKeysDir(t, files, "ed"); err != nil {
+		t.Fatalf("InitJWTKeys() error = %v", err)
+	}
+
+	issued, err := GenerateToken(1, "user@example.com", "user", "session")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	rsaPublicPEM := pkixPEM(t, &testRSAKey.PublicKey)
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"issued token", issued, false},
+		{"older key still verifies", signToken(t, jwt.SigningMethodRS256, "rsa", testRSAKey), false},
+		{"unknown kid", signToken(t, jwt.SigningMethodEdDSA, "unknown", testEd25519Key), true},
+		{"no kid", signToken(t, jwt.SigningMethodEdDSA, "", testEd25519Key), true},
+		{"alg of another key", signToken(t, jwt.SigningMethodEdDSA, "rsa", testEd25519Key), true},
+		{"RS512 with an RS256 key", signToken(t, jwt.SigningMethodRS512, "rsa", testRSAKey), true},
+		{"HS256 with the RSA public key PEM", signToken(t, jwt.SigningMethodHS256, "rsa", rsaPublicPEM), true},
+		{"HS256 with the RSA public key DER", signToken(t, jwt.SigningMethodHS256, "rsa", rsaPublicDER), true},
+		{"HS256 with the Ed25519 public key", signToken(t, jwt.SigningMethodHS256, "ed", []byte(testEd25519Key.Public().(ed25519.PublicKey))), true},
+		{"HS256 without kid", signToken(t, jwt.SigningMethodHS256, "", []byte("secret")), true},
+		{"alg none", signToken(t, jwt.SigningMethodNone, "ed", jwt.UnsafeAllowNoneSignatureType), true},
+		{"signed by another Ed25519 key", signToken(t, jwt.SigningMethodEdDSA, "ed", mustEd25519Key()), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateToken() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTokenSecret(t *testing.T) {
+	keepJWTKeys(t)
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "secret")
+	if err := InitJWTKeys(); err != nil {
+		t.Fatalf("InitJWTKeys() error = %v", err)
+	}
+
+	issued, err := GenerateToken(1, "user@example.com", "user", "session")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"issued token", issued, false},
+		{"HS256 without kid", signToken(t, jwt.SigningMethodHS256, "", []byte("secret")), false},
+		{"HS256 with a kid", signToken(t, jwt.SigningMethodHS256, "secret", []byte("secret")), true},
+		{"wrong secret", signToken(t, jwt.SigningMethodHS256, "", []byte("other")), true},
+		{"HS512", signToken(t, jwt.SigningMethodHS512, "", []byte("secret")), true},
+		{"alg none", signToken(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateToken() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTokenIssuer(t *testing.T) {
+	keepJWTKeys(t)
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "secret")
+	if err := InitJWTKeys(); err != nil {
+		t.Fatalf("InitJWTKeys() error = %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    "someone-else",
+		},
+	})
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateToken(signed); err == nil {
+		t.Error("ValidateToken() accepted a token from another issuer")
+	}
+}
+
+func TestBuildJWKS(t *testing.T) {
+	keys := map[string]*jwtKey{}
+	for kid, data := range map[string][]byte{
+		"b-rsa":    pkcs8PEM(t, testRSAKey),
+		"a-ed":     pkixPEM(t, testEd25519Key.Public()),
+		"c-public": pkixPEM(t, &testRSAKey.PublicKey),
+	} {
+		key, err := parseJWTKey(kid, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[kid] = key
+	}
+
+	set := buildJWKS(keys)
+	if len(set.Keys) != 3 {
+		t.Fatalf("got %d keys, want 3", len(set.Keys))
+	}
+
+	ed, rsaKey := set.Keys[0], set.Keys[1]
+	if ed.Kid != "a-ed" || rsaKey.Kid != "b-rsa" || set.Keys[2].Kid != "c-public" {
+		t.Errorf("keys not sorted by kid: %q, %q, %q", ed.Kid, rsaKey.Kid, set.Keys[2].Kid)
+	}
+
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" || len(ed.X) != 43 || ed.N != "" {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+	if rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.E != "AQAB" || len(rsaKey.N) != 342 || rsaKey.X != "" {
+		t.Errorf("RSA JWK = %+v", rsaKey)
+	}
+}
@@ -1,41 +1,55 @@
 package utils
 
 import (
-	"os"
+	"errors"
+	"fmt"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v4"
 )
 
+// Access tokens are short-lived, clients renew them with their refresh token
+const AccessTokenTTL = 15 * time.Minute
+
+// Issuer of the access tokens (iss claim)
+const tokenIssuer = "gosport-api"
+
 // Structure for JWT payload
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"` // login session, checked on every request so the token can be revoked
 	jwt.RegisteredClaims
 }
 
-// Generate JWT token
-func GenerateToken(userID uint, email, role string) (string, error) {
-	// JWT from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
+// Generate JWT access token for a login session, signed with the current key (see InitJWTKeys)
+func GenerateToken(userID uint, email, role, sessionID string) (string, error) {
+	key := jwtSigningKey
+	if key == nil {
+		return "", errors.New("JWT keys not initialized")
+	}
 
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hours expiration
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "gosport-api",
+			Issuer:    tokenIssuer,
 		},
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Create token, the kid header names the key verifying it
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
 
 	// Sign token
-	signedToken, err := token.SignedString([]byte(jwtSecret))
+	signedToken, err := token.SignedString(key.sign)
 	if err != nil {
 		return "", err
 	}
@@ -43,13 +57,21 @@ func GenerateToken(userID uint, email, role string) (string, error) {
 	return signedToken, nil
 }
 
+// ValidateToken verifies an access token with the key named by its kid header.
+// The algorithm must be the key's one (no "none", no HS256 with a public key).
 func ValidateToken(signedToken string) (*Claims, error) {
-	// JWT from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	// Parse token
 	token, err := jwt.ParseWithClaims(signedToken, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := jwtKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return key.verify, nil
 	})
 
 	if err != nil {
@@ -57,7 +79,7 @@ func ValidateToken(signedToken string) (*Claims, error) {
 	}
 
 	// Check claims
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Issuer == tokenIssuer {
 		return claims, nil
 	}
 
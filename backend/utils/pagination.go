@@ -2,6 +2,7 @@ package utils
 
 import (
 	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -71,3 +72,63 @@ func PaginatedResponse(c *fiber.Ctx, data interface{}, pagination PaginationMeta
 		"pagination": pagination,
 	})
 }
+
+// Cursor pagination parameters (for lists that change while being paged through)
+type CursorParams struct {
+	Cursor uint `json:"cursor"` // ID of the last item of the previous page, 0 for the first page
+	Limit  int  `json:"limit"`
+}
+
+// Cursor pagination metadata in response
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"` // pass as ?cursor= to get the next page
+	HasNext    bool   `json:"has_next"`
+}
+
+// Extracts and validates cursor pagination parameters from query (?cursor=&limit=)
+func ParseCursor(c *fiber.Ctx) (CursorParams, error) {
+	limit := c.QueryInt("limit", 20)
+
+	// Validate limit
+	if limit < 1 {
+		limit = 10
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	var cursor uint
+	if raw := c.Query("cursor"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || id == 0 {
+			return CursorParams{}, fiber.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		cursor = uint(id)
+	}
+
+	return CursorParams{
+		Cursor: cursor,
+		Limit:  limit,
+	}, nil
+}
+
+// Creates cursor metadata from the ID of the last item returned, when more items follow
+func CreateCursorMeta(limit int, lastID uint, hasNext bool) CursorMeta {
+	meta := CursorMeta{
+		Limit:   limit,
+		HasNext: hasNext,
+	}
+	if hasNext {
+		meta.NextCursor = strconv.FormatUint(uint64(lastID), 10)
+	}
+	return meta
+}
+
+// Creates a standardized cursor paginated response
+func CursorPaginatedResponse(c *fiber.Ctx, data interface{}, pagination CursorMeta) error {
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       data,
+		"pagination": pagination,
+	})
+}
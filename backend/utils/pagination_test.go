@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// Runs fn with a request context for the given query string
+func withQuery(t *testing.T, query string, fn func(c *fiber.Ctx)) {
+	t.Helper()
+
+	app := fiber.New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Request().SetRequestURI("/comments?" + query)
+	fn(c)
+}
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantCursor uint
+		wantLimit  int
+		wantErr    bool
+	}{
+		{"defaults", "", 0, 20, false},
+		{"cursor", "cursor=42", 42, 20, false},
+		{"cursor and limit", "cursor=42&limit=5", 42, 5, false},
+		{"empty cursor is the first page", "cursor=", 0, 20, false},
+		{"max uint64 cursor", "cursor=18446744073709551615", 18446744073709551615, 20, false},
+
+		// Limit clamping
+		{"limit 1", "limit=1", 0, 1, false},
+		{"limit 100", "limit=100", 0, 100, false},
+		{"limit above max", "limit=101", 0, 100, false},
+		{"huge limit", "limit=999999", 0, 100, false},
+		{"zero limit", "limit=0", 0, 10, false},
+		{"negative limit", "limit=-5", 0, 10, false},
+		{"non-numeric limit", "limit=abc", 0, 20, false},
+
+		// Malformed or tampered cursors
+		{"zero cursor", "cursor=0", 0, 0, true},
+		{"negative cursor", "cursor=-1", 0, 0, true},
+		{"non-numeric cursor", "cursor=abc", 0, 0, true},
+		{"decimal cursor", "cursor=1.5", 0, 0, true},
+		{"trailing garbage", "cursor=5abc", 0, 0, true},
+		{"hex cursor", "cursor=0x10", 0, 0, true},
+		{"overflowing cursor", "cursor=18446744073709551616", 0, 0, true},
+		{"sql in cursor", "cursor=1%20OR%201=1", 0, 0, true},
+		{"spaces in cursor", "cursor=%205", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withQuery(t, tt.query, func(c *fiber.Ctx) {
+				params, err := ParseCursor(c)
+				if tt.wantErr {
+					var fiberErr *fiber.Error
+					if !errors.As(err, &fiberErr) || fiberErr.Code != fiber.StatusBadRequest {
+						t.Fatalf("ParseCursor(%q) error = %v, want a 400 *fiber.Error", tt.query, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ParseCursor(%q) error = %v", tt.query, err)
+				}
+				if params.Cursor != tt.wantCursor || params.Limit != tt.wantLimit {
+					t.Errorf("ParseCursor(%q) = %+v, want cursor %d, limit %d", tt.query, params, tt.wantCursor, tt.wantLimit)
+				}
+			})
+		})
+	}
+}
+
+func TestCreateCursorMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		lastID  uint
+		hasNext bool
+		want    CursorMeta
+	}{
+		{"more items follow", 20, 981, true, CursorMeta{Limit: 20, NextCursor: "981", HasNext: true}},
+		{"last page", 20, 981, false, CursorMeta{Limit: 20}},
+		{"empty page", 20, 0, false, CursorMeta{Limit: 20}},
+		{"single item pages", 1, 7, true, CursorMeta{Limit: 1, NextCursor: "7", HasNext: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CreateCursorMeta(tt.limit, tt.lastID, tt.hasNext); got != tt.want {
+				t.Errorf("CreateCursorMeta() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The next cursor is accepted by ParseCursor as is
+	meta := CreateCursorMeta(20, 981, true)
+	withQuery(t, "cursor="+meta.NextCursor, func(c *fiber.Ctx) {
+		params, err := ParseCursor(c)
+		if err != nil || params.Cursor != 981 {
+			t.Errorf("ParseCursor(next_cursor) = %+v, %v, want cursor 981", params, err)
+		}
+	})
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query string
+		want  PaginationParams
+	}{
+		{"", PaginationParams{Page: 1, Limit: 20, Offset: 0}},
+		{"page=3&limit=10", PaginationParams{Page: 3, Limit: 10, Offset: 20}},
+		{"page=0", PaginationParams{Page: 1, Limit: 20, Offset: 0}},
+		{"page=-2&limit=500", PaginationParams{Page: 1, Limit: 100, Offset: 0}},
+		{"page=2&limit=0", PaginationParams{Page: 2, Limit: 10, Offset: 10}},
+	}
+
+	for _, tt := range tests {
+		withQuery(t, tt.query, func(c *fiber.Ctx) {
+			if got := ParsePagination(c); got != tt.want {
+				t.Errorf("ParsePagination(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
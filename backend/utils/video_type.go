@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Number of leading bytes needed to detect the container of a video file
+const VideoSniffLength = 512
+
+// ISO base media brands of MP4 / QuickTime video files (images such as HEIC/AVIF share the container, so brands are whitelisted)
+var mp4Brands = map[string]string{
+	"isom": "video/mp4",
+	"iso2": "video/mp4",
+	"iso3": "video/mp4",
+	"iso4": "video/mp4",
+	"iso5": "video/mp4",
+	"iso6": "video/mp4",
+	"mp41": "video/mp4",
+	"mp42": "video/mp4",
+	"mp71": "video/mp4",
+	"avc1": "video/mp4",
+	"dash": "video/mp4",
+	"mmp4": "video/mp4",
+	"MSNV": "video/mp4",
+	"NDAS": "video/mp4",
+	"XAVC": "video/mp4",
+	"M4V ": "video/mp4",
+	"M4VH": "video/mp4",
+	"M4VP": "video/mp4",
+	"f4v ": "video/mp4",
+	"qt  ": "video/quicktime",
+}
+
+// Atoms a QuickTime file may start with when it has no "ftyp" atom (files from older encoders)
+var quickTimeAtoms = map[string]bool{
+	"moov": true,
+	"mdat": true,
+	"wide": true,
+	"free": true,
+	"skip": true,
+	"pnot": true,
+}
+
+// DetectVideoType detects the container of a video file by its magic bytes (MP4, MOV, MKV, WebM or AVI)
+// and returns its MIME type. header should hold the first VideoSniffLength bytes of the file.
+func DetectVideoType(header []byte) (string, bool) {
+	switch {
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return detectISOBMFF(header)
+
+	case len(header) >= 8 && quickTimeAtoms[string(header[4:8])]:
+		return "video/quicktime", true
+
+	case len(header) >= 4 && bytes.Equal(header[:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return detectEBML(header)
+
+	case len(header) >= 12 && bytes.Equal(header[:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("AVI ")):
+		return "video/x-msvideo", true
+	}
+
+	return "", false
+}
+
+// MP4 / MOV: the "ftyp" atom holds the major brand followed by the compatible ones
+func detectISOBMFF(header []byte) (string, bool) {
+	if mimeType, ok := mp4Brands[string(header[8:12])]; ok {
+		return mimeType, true
+	}
+
+	// Layout: size (4) + "ftyp" (4) + major brand (4) + minor version (4) + compatible brands (4 each)
+	size := int(binary.BigEndian.Uint32(header[:4]))
+	if size > len(header) {
+		size = len(header)
+	}
+	for offset := 16; offset+4 <= size; offset += 4 {
+		if mimeType, ok := mp4Brands[string(header[offset:offset+4])]; ok {
+			return mimeType, true
+		}
+	}
+
+	return "", false
+}
+
+// MKV / WebM: the EBML header holds the document type near the start of the file
+func detectEBML(header []byte) (string, bool) {
+	if len(header) > 64 {
+		header = header[:64]
+	}
+
+	switch {
+	case bytes.Contains(header, []byte("webm")):
+		return "video/webm", true
+	case bytes.Contains(header, []byte("matroska")):
+		return "video/x-matroska", true
+	}
+
+	return "", false
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// ftyp atom with a major brand and compatible brands, padded like the start of a real file
+func ftyp(major string, compatible ...string) []byte {
+	size := 16 + 4*len(compatible)
+	atom := make([]byte, 0, VideoSniffLength)
+	atom = binary.BigEndian.AppendUint32(atom, uint32(size))
+	atom = append(atom, "ftyp"...)
+	atom = append(atom, major...)
+	atom = append(atom, 0, 0, 0, 0) // minor version
+	for _, brand := range compatible {
+		atom = append(atom, brand...)
+	}
+	return append(atom, "\x00\x00\x00\x08free"...)
+}
+
+// EBML header declaring a document type
+func ebml(docType string) []byte {
+	header := []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81, 0x01, 0x42, 0x82, 0x80 | byte(len(docType))}
+	return append(header, docType...)
+}
+
+func TestDetectVideoType(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		wantType string
+		wantOK   bool
+	}{
+		// MP4 / MOV
+		{"mp4 isom", ftyp("isom", "isom", "iso2", "avc1", "mp41"), "video/mp4", true},
+		{"mp4 mp42", ftyp("mp42"), "video/mp4", true},
+		{"m4v", ftyp("M4V ", "M4V ", "mp42"), "video/mp4", true},
+		{"mp4 by compatible brand", ftyp("abcd", "efgh", "mp41"), "video/mp4", true},
+		{"mov ftyp", ftyp("qt  ", "qt  "), "video/quicktime", true},
+		{"mov without ftyp (moov)", []byte("\x00\x00\x00\x6cmoov\x00\x00\x00\x6cmvhd"), "video/quicktime", true},
+		{"mov without ftyp (mdat)", []byte("\x00\x00\x10\x00mdat"), "video/quicktime", true},
+		{"heic image", ftyp("heic", "mif1", "heic"), "", false},
+		{"avif image", ftyp("avif", "mif1", "miaf"), "", false},
+		{"compatible brand past the atom size", append(ftyp("abcd"), "mp41"...), "", false},
+
+		// MKV / WebM
+		{"webm", ebml("webm"), "video/webm", true},
+		{"mkv", ebml("matroska"), "video/x-matroska", true},
+		{"ebml with another doc type", ebml("other"), "", false},
+		{"ebml magic only", []byte{0x1A, 0x45, 0xDF, 0xA3}, "", false},
+
+		// AVI
+		{"avi", []byte("RIFF\x24\x00\x10\x00AVI LIST"), "video/x-msvideo", true},
+		{"wav is riff too", []byte("RIFF\x24\x00\x10\x00WAVEfmt "), "", false},
+
+		// Truncated
+		{"empty", nil, "", false},
+		{"truncated ftyp", []byte("\x00\x00\x00\x18ftyp"), "", false},
+		{"truncated ftyp brand", []byte("\x00\x00\x00\x18ftypis"), "", false},
+		{"truncated atom", []byte("\x00\x00\x00"), "", false},
+		{"truncated ebml", []byte{0x1A, 0x45, 0xDF}, "", false},
+		{"truncated riff", []byte("RIFF\x24\x00\x10\x00AV"), "", false},
+
+		// Not video
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "", false},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "", false},
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3"), "", false},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), "", false},
+		{"text", []byte("hello, this is not a video at all"), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotOK := DetectVideoType(tt.header)
+			if gotType != tt.wantType || gotOK != tt.wantOK {
+				t.Errorf("DetectVideoType() = (%q, %v), want (%q, %v)", gotType, gotOK, tt.wantType, tt.wantOK)
+			}
+		})
+	}
+}
@@ -45,6 +45,14 @@ func RunMigrations() {
 		&models.Comment{},
 		&models.ProcessingJob{},
 		&models.RSSFeed{},
+		&models.MediaInfo{},
+		&models.Upload{},
+		&models.UploadPart{},
+		&models.CleanupJob{},
+		&models.Reaction{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.LoginEvent{},
 	)
 
 	if err != nil {
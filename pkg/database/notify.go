@@ -0,0 +1,24 @@
+package database
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Postgres channel the video worker LISTENs on for new processing jobs
+const JobsChannel = "processing_jobs"
+
+// NotifyJobQueued wakes up listening workers (payload is the job ID)
+func NotifyJobQueued(db *gorm.DB, jobID uint) error {
+	return db.Exec("SELECT pg_notify(?, ?)", JobsChannel, strconv.FormatUint(uint64(jobID), 10)).Error
+}
+
+// Postgres channel the API LISTENs on for processing status and progress changes
+const JobProgressChannel = "processing_job_progress"
+
+// NotifyJobProgress tells listening API instances that a job of the video changed (payload is the video ID).
+// Sent within a transaction, it is delivered on commit.
+func NotifyJobProgress(db *gorm.DB, videoID uint) error {
+	return db.Exec("SELECT pg_notify(?, ?)", JobProgressChannel, strconv.FormatUint(uint64(videoID), 10)).Error
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Removal of the stored files of a deleted video (run by the API with retries, Logs is the audit trail)
+type CleanupJob struct {
+	ID          uint     `gorm:"primaryKey" json:"id"`
+	VideoID     uint     `gorm:"not null;index" json:"video_id"`            // the video row is already gone
+	RequestedBy uint     `json:"requested_by"`                              // user who deleted the video
+	Objects     []string `gorm:"type:text;serializer:json" json:"objects"`  // single objects: videos/uuid.mp4, ...
+	Prefixes    []string `gorm:"type:text;serializer:json" json:"prefixes"` // folders: videos/hls/id/, videos/thumbnails/id/
+	Status      string   `gorm:"default:queued;index" json:"status"`        // queued, running, completed, dead (retries exhausted)
+	Logs        string   `gorm:"type:text" json:"logs"`                     // what was removed or failed, one timestamped line per event
+
+	// Retry policy
+	Attempts      int        `gorm:"default:0;not null" json:"attempts"`
+	MaxAttempts   int        `gorm:"default:5;not null" json:"max_attempts"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+
+	// Running jobs whose lease expired (instance stopped mid-run) are claimed again
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+
+	DeletedObjects int        `gorm:"default:0" json:"deleted_objects"` // objects removed over all attempts
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -5,14 +5,18 @@ import (
 )
 
 type Comment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	VideoID   uint      `gorm:"not null;index" json:"video_id"`
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	VideoID    uint       `gorm:"not null;index" json:"video_id"`
+	ParentID   *uint      `gorm:"index" json:"parent_id,omitempty"` // top-level comment replied to (replies are one level deep)
+	Content    string     `gorm:"type:text;not null" json:"content"`
+	Pinned     bool       `gorm:"default:false" json:"pinned"`  // pinned by the video creator (one per video)
+	ReplyCount int        `gorm:"default:0" json:"reply_count"` // kept in sync with the replies
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 
 	// Relations
 	User  User  `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Video Video `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+	Video Video `gorm:"foreignKey:VideoID" json:"-"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Login attempt, successful or not (login history of the users, brute-force investigations)
+type LoginEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    *uint     `gorm:"index" json:"-"` // nil when the email matches no account
+	Email     string    `gorm:"index" json:"-"` // email the attempt was made with
+	IP        string    `gorm:"index" json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`     // failures: bad_password, unknown_email, locked
+	SessionID string    `json:"session_id,omitempty"` // session opened by a successful login
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
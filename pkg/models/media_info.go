@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Technical metadata of the uploaded source file (filled by the video worker with ffprobe)
+type MediaInfo struct {
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	VideoID    uint    `gorm:"not null;uniqueIndex" json:"video_id"`
+	FormatName string  `json:"format_name"` // mov,mp4,m4a,3gp,3g2,mj2 / matroska,webm / avi
+	Duration   float64 `json:"duration"`    // seconds
+	BitRate    int64   `json:"bit_rate"`    // bits per second (whole container)
+
+	// Video stream
+	Width        int     `json:"width"`
+	Height       int     `json:"height"`
+	VideoCodec   string  `json:"video_codec"`   // h264, hevc, vp9, etc.
+	VideoProfile string  `json:"video_profile"` // High, Main, etc.
+	PixelFormat  string  `json:"pixel_format"`  // yuv420p, etc.
+	FrameRate    float64 `json:"frame_rate"`    // frames per second
+	Rotation     int     `json:"rotation"`      // degrees (phones record portrait videos as rotated landscape)
+
+	// Audio (first track)
+	AudioCodec      string `json:"audio_codec,omitempty"` // aac, opus, etc.
+	AudioChannels   int    `json:"audio_channels,omitempty"`
+	AudioSampleRate int    `json:"audio_sample_rate,omitempty"` // Hz
+	AudioTracks     int    `gorm:"default:0" json:"audio_tracks"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -3,10 +3,27 @@ package models
 import "time"
 
 type ProcessingJob struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	VideoID   uint      `gorm:"not null;index" json:"video_id"`
-	Status    string    `gorm:"default:pending" json:"status"` // pending, processing, completed, failed
-	Logs      string    `gorm:"type:text" json:"logs"`         // Processing logs or error messages
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	VideoID uint   `gorm:"not null;index" json:"video_id"`
+	Status  string `gorm:"default:pending" json:"status"` // queued, processing, completed, failed (rejected upload), dead (retries exhausted)
+	Logs    string `gorm:"type:text" json:"logs"`         // Processing logs or error messages
+
+	// Retry policy
+	Attempts      int        `gorm:"default:0;not null" json:"attempts"`     // Number of times a worker picked up the job
+	MaxAttempts   int        `gorm:"default:5;not null" json:"max_attempts"` // Job moves to "dead" once Attempts reaches it
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"` // Job is not picked up before this time (backoff)
+
+	// Lease (a job whose worker stops heartbeating is requeued once the lease expires)
+	LockedBy       string     `json:"locked_by,omitempty"`                     // Worker holding the job (hostname-pid)
+	LeaseExpiresAt *time.Time `gorm:"index" json:"lease_expires_at,omitempty"` // Extended by every heartbeat
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`                  // Last heartbeat from the worker
+
+	// Progress of the current attempt (reported by the worker)
+	Stage      string     `json:"stage,omitempty"`           // download, probe, transcode, thumbnails, upload
+	Progress   float64    `gorm:"default:0" json:"progress"` // Overall percentage (0-100)
+	ETASeconds *int       `json:"eta_seconds,omitempty"`     // Estimated seconds left
+	StartedAt  *time.Time `json:"started_at,omitempty"`      // When the current attempt was claimed
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
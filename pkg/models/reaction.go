@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Reaction of a user to a video, at most one per (user, video)
+type Reaction struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	VideoID   uint      `gorm:"primaryKey;autoIncrement:false;index" json:"video_id"`
+	Value     string    `gorm:"not null" json:"value"` // like, dislike
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relations
+	User  User  `gorm:"foreignKey:UserID" json:"-"`
+	Video Video `gorm:"foreignKey:VideoID" json:"video,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Login session, renewed with a rotating refresh token (only its SHA-256 hash is stored).
+// Access tokens carry the session ID and stop working once the session is revoked.
+type Session struct {
+	ID                string     `gorm:"primaryKey;type:varchar(36)" json:"id"` // UUID, "sid" claim of the access tokens
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"not null;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"index" json:"-"` // last rotated token, presenting it again means it was stolen (past a short grace period)
+	RotationKey       string     `json:"-"`              // secret the next refresh token is derived with
+	IP                string     `json:"ip"`
+	UserAgent         string     `json:"user_agent"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason     string     `json:"revoked_reason,omitempty"` // logout, logout_all, password_change, password_reset, refresh_token_reuse
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Upload session: either resumable (chunks backed by a MinIO multipart upload)
+// or presigned (the client sends the file straight to MinIO, then finalizes it)
+type Upload struct {
+	ID            string `gorm:"primaryKey;type:varchar(36)" json:"id"` // uuid (unguessable, used in URLs)
+	UserID        uint   `gorm:"not null;index" json:"user_id"`
+	Method        string `gorm:"default:chunked" json:"method"` // chunked, presigned
+	MinioKey      string `gorm:"not null" json:"-"`             // videos/uuid.mp4
+	MinioUploadID string `json:"-"`                             // MinIO multipart upload ID (chunked only)
+
+	// File
+	FileName    string `json:"file_name"`
+	FileSize    int64  `json:"file_size"`    // total size in bytes
+	MimeType    string `json:"mime_type"`    // video/mp4
+	ChunkSize   int64  `json:"chunk_size"`   // every chunk has this size except the last one (chunked only)
+	TotalChunks int    `json:"total_chunks"` // chunks are numbered 0..TotalChunks-1 (chunked only)
+
+	// Video metadata (used to create the Video on completion)
+	Title       string     `json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	Sport       string     `json:"sport"`
+	Visibility  string     `json:"visibility"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+
+	Status    string    `gorm:"default:active;index" json:"status"` // active, completing, completed, aborted
+	VideoID   *uint     `json:"video_id,omitempty"`                 // set once completed
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`            // active uploads are aborted after this
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Parts []UploadPart `gorm:"foreignKey:UploadID" json:"-"`
+}
+
+// Chunk received for an upload (one MinIO multipart part)
+type UploadPart struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UploadID   string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_upload_part" json:"upload_id"`
+	PartNumber int       `gorm:"not null;uniqueIndex:idx_upload_part" json:"part_number"` // chunk index + 1 (MinIO parts start at 1)
+	Size       int64     `json:"size"`
+	ETag       string    `json:"etag"`
+	CreatedAt  time.Time `json:"created_at"`
+}
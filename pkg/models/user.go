@@ -18,6 +18,11 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"` // soft delete
 
+	// Login security
+	FailedLogins      int        `gorm:"default:0" json:"-"` // consecutive failed logins, reset by a successful one or a quiet day
+	LockedUntil       *time.Time `json:"-"`                  // progressive lockout after repeated failures
+	LastFailedLoginAt *time.Time `json:"-"`
+
 	// Relations
 	Videos        []Video        `gorm:"foreignKey:UserID" json:"videos,omitempty"`
 	Subscriptions []Subscription `gorm:"foreignKey: SubscriberID" json:"subscriptions,omitempty"`
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Single-use token emailed to a user (only its SHA-256 hash is stored)
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"not null;index" json:"purpose"` // email_verification, password_reset
+	TokenHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -16,21 +16,32 @@ type Video struct {
 	FileName  string `json:"file_name"` // original filename
 	FileSize  int64  `json:"file_size"` // file size in bytes
 	MimeType  string `json:"mime_type"` // video/mp4
-	HLSPath   string `json:"hls_path"`  // videos/uuid/playlist.m3u8
-	Thumbnail string `json:"thumbnail"` // thumbnails/uuid.jpg
+	HLSPath   string `json:"hls_path"`  // videos/hls/id/master.m3u8
+	Thumbnail string `json:"thumbnail"` // videos/thumbnails/id/poster.jpg
+
+	// Artwork generated by the worker
+	ThumbnailCandidates []string `gorm:"type:text;serializer:json" json:"thumbnail_candidates,omitempty"` // videos/thumbnails/id/candidates/1.jpg, ...
+	PreviewVTT          string   `json:"preview_vtt,omitempty"`                                           // videos/thumbnails/id/previews.vtt (scrub preview sprites index)
+	ThumbnailURL        string   `gorm:"-" json:"thumbnail_url,omitempty"`                                // not in DB, presigned on read
 
 	// Metadata
 	Duration int    `json:"duration"`                      // seconds
 	Status   string `gorm:"default:pending" json:"status"` // pending, processing, ready, failed
 
+	// Publishing
+	Visibility string     `gorm:"default:public;index" json:"visibility"` // public (listed), unlisted (link only), private (owner only)
+	PublishAt  *time.Time `gorm:"index" json:"publish_at,omitempty"`      // scheduled publishing, only the owner sees the video before
+
 	// Stats
-	Views int `gorm:"default:0" json:"views"`
-	Likes int `gorm:"default:0" json:"likes"`
+	Views    int `gorm:"default:0" json:"views"`
+	Likes    int `gorm:"default:0" json:"likes"`    // kept in sync with the reactions
+	Dislikes int `gorm:"default:0" json:"dislikes"` // kept in sync with the reactions
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
 	// Relations
-	User     User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Comments []Comment `gorm:"foreignKey:VideoID" json:"comments,omitempty"`
+	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Comments  []Comment  `gorm:"foreignKey:VideoID" json:"comments,omitempty"`
+	MediaInfo *MediaInfo `gorm:"foreignKey:VideoID" json:"media_info,omitempty"`
 }
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Folder holding the parts of multipart uploads in progress (never listed)
+const multipartDir = ".multipart"
+
+// Content types of the stored files (mime.TypeByExtension covers the rest)
+var localContentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".jpg":  "image/jpeg",
+	".vtt":  "text/vtt",
+}
+
+// Directory on the local disk, for development and integration tests (no MinIO container needed).
+// The API and the worker must share the directory.
+//
+// Presigned URLs point at publicURL (served by the API, see Verify) and are signed with signingKey.
+type Local struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+func NewLocal(root, publicURL, signingKey string) (*Local, error) {
+	if root == "" {
+		return nil, errors.New("STORAGE_LOCAL_PATH is required for local storage")
+	}
+	if publicURL == "" {
+		publicURL = "/storage"
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Local{
+		root:       root,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// Maps a key to its file, rejecting keys escaping the root
+func (l *Local) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.HasPrefix(clean, "/"+multipartDir+"/") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
+
+// Writes to a temporary file first, so readers never see a partial object
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	return writeAtomically(file, r)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) GetRange(ctx context.Context, key string, start, end int64) (io.ReadCloser, error) {
+	reader, err := l.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	f := reader.(*os.File)
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, end-start+1), f}, nil
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	file, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l.objectInfo(key, info), nil
+}
+
+// URL of the API route serving the file: <publicURL>/<key>?expires=<unix>&signature=<hmac>
+func (l *Local) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if len(l.signingKey) == 0 {
+		return "", errors.New("STORAGE_SIGNING_KEY is required to presign local storage URLs")
+	}
+
+	expiresAt := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", l.sign(key, expiresAt))
+
+	return l.publicURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify checks the expiry and signature of a URL returned by PresignGet
+func (l *Local) Verify(key, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || len(l.signingKey) == 0 || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(l.sign(key, expiresAt)))
+}
+
+func (l *Local) sign(key string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	fmt.Fprintf(mac, "%s\n%d", key, expiresAt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := l.Walk(ctx, prefix, "", func(object ObjectInfo) bool {
+		objects = append(objects, object)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// WalkDir goes folder by folder ("a/b" comes before "a-c" although '-' < '/'), so the matching objects
+// are collected and sorted first: startAfter then pages the same way as on MinIO
+func (l *Local) Walk(ctx context.Context, prefix, startAfter string, fn func(ObjectInfo) bool) error {
+	// Walk from the deepest folder the prefix names, then filter on the full prefix
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = filepath.Join(l.root, filepath.FromSlash(path.Clean("/"+prefix[:i])))
+	}
+
+	var objects []ObjectInfo
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(l.root, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if key == multipartDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(d.Name(), ".tmp-") || key <= startAfter {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *l.objectInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	for _, object := range objects {
+		if !fn(object) {
+			break
+		}
+	}
+	return nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Parts are stored as <root>/.multipart/<upload id>/<part number>
+func (l *Local) partsDir(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", fmt.Errorf("invalid upload ID %q", uploadID)
+	}
+	return filepath.Join(l.root, multipartDir, uploadID), nil
+}
+
+func (l *Local) StartMultipart(ctx context.Context, key, contentType string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	dir, err := l.partsDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	return uploadID, os.MkdirAll(dir, 0755)
+}
+
+func (l *Local) PutPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (string, error) {
+	dir, err := l.partsDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("unknown upload %s: %w", uploadID, err)
+	}
+
+	hash := md5.New()
+	if err := writeAtomically(filepath.Join(dir, strconv.Itoa(number)), io.TeeReader(r, hash)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (l *Local) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	dir, err := l.partsDir(uploadID)
+	if err != nil {
+		return err
+	}
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Parts are concatenated in ascending order
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		numbers = append(numbers, part.Number)
+	}
+	sort.Ints(numbers)
+
+	readers := make([]io.Reader, 0, len(numbers))
+	for _, number := range numbers {
+		f, err := os.Open(filepath.Join(dir, strconv.Itoa(number)))
+		if err != nil {
+			return fmt.Errorf("missing part %d: %w", number, err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	if err := writeAtomically(file, io.MultiReader(readers...)); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (l *Local) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	dir, err := l.partsDir(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (l *Local) objectInfo(key string, info fs.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  localContentType(key),
+		ETag:         fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		LastModified: info.ModTime(),
+	}
+}
+
+// Content type from the extension (no metadata is stored next to the files)
+func localContentType(key string) string {
+	ext := strings.ToLower(path.Ext(key))
+	if contentType, ok := localContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// Writes r to a temporary file next to file, then renames it into place
+func writeAtomically(file string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
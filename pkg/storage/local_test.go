@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestLocal(t *testing.T, keys ...string) *Local {
+	t.Helper()
+
+	l, err := NewLocal(t.TempDir(), "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if err := l.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+	return l
+}
+
+// Keys the walk yields, stopping after limit objects (0 for all)
+func walkKeys(t *testing.T, l *Local, prefix, startAfter string, limit int) []string {
+	t.Helper()
+
+	var keys []string
+	err := l.Walk(context.Background(), prefix, startAfter, func(object ObjectInfo) bool {
+		keys = append(keys, object.Key)
+		return limit == 0 || len(keys) < limit
+	})
+	if err != nil {
+		t.Fatalf("Walk(%q, %q) error = %v", prefix, startAfter, err)
+	}
+	return keys
+}
+
+func TestLocalWalkKeyOrder(t *testing.T) {
+	// '-' (0x2d) sorts before '/' (0x2f): "a-c" comes before "a/b", unlike the folder by folder walk
+	l := newTestLocal(t, "a/b", "a-c", "a/c/d", "a0", "b", "a.txt")
+
+	want := []string{"a-c", "a.txt", "a/b", "a/c/d", "a0", "b"}
+	if got := walkKeys(t, l, "", "", 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		prefix     string
+		startAfter string
+		want       []string
+	}{
+		{"after a-c", "", "a-c", []string{"a.txt", "a/b", "a/c/d", "a0", "b"}},
+		{"after a/b", "", "a/b", []string{"a/c/d", "a0", "b"}},
+		{"after a missing key", "", "a/a", []string{"a/b", "a/c/d", "a0", "b"}},
+		{"prefix a", "a", "", []string{"a-c", "a.txt", "a/b", "a/c/d", "a0"}},
+		{"prefix a/", "a/", "", []string{"a/b", "a/c/d"}},
+		{"prefix a/ after a/b", "a/", "a/b", []string{"a/c/d"}},
+		{"after the last key", "", "b", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := walkKeys(t, l, tt.prefix, tt.startAfter, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Walk(%q, %q) = %v, want %v", tt.prefix, tt.startAfter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalWalkPaging(t *testing.T) {
+	keys := []string{"a/b", "a-c", "a/c/d", "a0", "b", "a.txt", "videos/hls/1/master.m3u8", "videos/hls-old/x"}
+	l := newTestLocal(t, keys...)
+
+	// Pages of 2, each starting after the last key of the previous one, see every key once
+	var seen []string
+	after := ""
+	for {
+		page := walkKeys(t, l, "", after, 2)
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page...)
+		after = page[len(page)-1]
+	}
+
+	if want := walkKeys(t, l, "", "", 0); !reflect.DeepEqual(seen, want) || len(seen) != len(keys) {
+		t.Errorf("paged walk = %v, want %v", seen, want)
+	}
+}
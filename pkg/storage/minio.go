@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// MinIO (or any S3 compatible) bucket
+type Minio struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewMinio(client *minio.Client, bucket string) *Minio {
+	return &Minio{client: client, bucket: bucket}
+}
+
+func (m *Minio) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := m.client.PutObject(ctx, m.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (m *Minio) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return m.getObject(ctx, key, minio.GetObjectOptions{})
+}
+
+func (m *Minio) GetRange(ctx context.Context, key string, start, end int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return nil, err
+	}
+	return m.getObject(ctx, key, opts)
+}
+
+// GetObject is lazy, the first request is made by Stat so missing keys fail here with ErrNotFound (like the local driver)
+func (m *Minio) getObject(ctx context.Context, key string, opts minio.GetObjectOptions) (io.ReadCloser, error) {
+	obj, err := m.client.GetObject(ctx, m.bucket, key, opts)
+	if err != nil {
+		return nil, minioError(err)
+	}
+
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, minioError(err)
+	}
+	return obj, nil
+}
+
+func (m *Minio) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := m.client.StatObject(ctx, m.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return minioObjectInfo(info), nil
+}
+
+func (m *Minio) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	url, err := m.client.PresignedGetObject(ctx, m.bucket, key, expires, nil)
+	if err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
+
+func (m *Minio) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := m.Walk(ctx, prefix, "", func(object ObjectInfo) bool {
+		objects = append(objects, object)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (m *Minio) Walk(ctx context.Context, prefix, startAfter string, fn func(ObjectInfo) bool) error {
+	// Stops the listing goroutine when fn ends the walk early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for object := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: startAfter,
+		Recursive:  true,
+	}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if !fn(*minioObjectInfo(object)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *Minio) Delete(ctx context.Context, key string) error {
+	return m.client.RemoveObject(ctx, m.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// Low-level MinIO API (multipart uploads are not exposed by minio.Client)
+func (m *Minio) core() minio.Core {
+	return minio.Core{Client: m.client}
+}
+
+func (m *Minio) StartMultipart(ctx context.Context, key, contentType string) (string, error) {
+	return m.core().NewMultipartUpload(ctx, m.bucket, key, minio.PutObjectOptions{ContentType: contentType})
+}
+
+func (m *Minio) PutPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (string, error) {
+	part, err := m.core().PutObjectPart(ctx, m.bucket, key, uploadID, number, r, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+func (m *Minio) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error {
+	// MinIO requires the parts in ascending order
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{
+			PartNumber: part.Number,
+			ETag:       part.ETag,
+		})
+	}
+	sort.Slice(completeParts, func(i, j int) bool {
+		return completeParts[i].PartNumber < completeParts[j].PartNumber
+	})
+
+	_, err := m.core().CompleteMultipartUpload(ctx, m.bucket, key, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+func (m *Minio) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	return minioError(m.core().AbortMultipartUpload(ctx, m.bucket, key, uploadID))
+}
+
+// The policy only accepts key with exactly this content type and size
+func (m *Minio) PresignPost(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(m.bucket); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(key); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expires)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(size, size); err != nil {
+		return "", nil, err
+	}
+
+	url, formData, err := m.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return url.String(), formData, nil
+}
+
+func minioObjectInfo(info minio.ObjectInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
+
+// Maps missing keys to ErrNotFound
+func minioError(err error) error {
+	if code := minio.ToErrorResponse(err).Code; code == "NoSuchKey" || code == "NotFound" || code == "NoSuchUpload" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/config"
+)
+
+var (
+	ErrNotFound     = errors.New("object not found")
+	ErrNotSupported = errors.New("not supported by the storage driver")
+)
+
+// Metadata of a stored object
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Storage is the blob store holding uploads and everything the worker derives from them.
+// Keys are slash-separated paths (videos/uuid.mp4, videos/hls/123/master.m3u8).
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)                        // ErrNotFound for missing keys
+	GetRange(ctx context.Context, key string, start, end int64) (io.ReadCloser, error) // bytes start..end (inclusive), ErrNotFound for missing keys
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)                         // ErrNotFound for missing keys
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error) // recursive
+	Delete(ctx context.Context, key string) error                  // missing keys are not an error
+	// Walk calls fn for every object under prefix with a key after startAfter ("" for all), in key order,
+	// without loading the listing in memory. It stops early when fn returns false.
+	Walk(ctx context.Context, prefix, startAfter string, fn func(ObjectInfo) bool) error
+}
+
+// Part of a multipart upload
+type Part struct {
+	Number int    // starts at 1
+	ETag   string // returned by PutPart
+}
+
+// Multipart is implemented by drivers that can assemble an object from parts uploaded separately
+type Multipart interface {
+	StartMultipart(ctx context.Context, key, contentType string) (string, error)
+	PutPart(ctx context.Context, key, uploadID string, number int, r io.Reader, size int64) (string, error)
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error // ErrNotFound once completed or aborted (MinIO)
+}
+
+// PostPolicyPresigner is implemented by drivers browsers can upload to directly (HTML form POST)
+type PostPolicyPresigner interface {
+	PresignPost(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, map[string]string, error)
+}
+
+// Default is the storage used by the API and the workers, set by Init
+var Default Storage
+
+// Init creates the driver selected by STORAGE_DRIVER: "minio" (default) or "local"
+func Init() error {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "minio":
+		if err := config.InitMinio(); err != nil {
+			return err
+		}
+		Default = NewMinio(config.MinioClient, os.Getenv("MINIO_BUCKET_NAME"))
+
+	case "local":
+		local, err := NewLocal(os.Getenv("STORAGE_LOCAL_PATH"), os.Getenv("STORAGE_PUBLIC_URL"), os.Getenv("STORAGE_SIGNING_KEY"))
+		if err != nil {
+			return err
+		}
+		Default = local
+		log.Printf("Using local storage in %s", local.root)
+
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER %q (allowed: minio, local)", driver)
+	}
+
+	return nil
+}
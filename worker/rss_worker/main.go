@@ -6,7 +6,7 @@ import (
 
 	"github.com/robfig/cron/v3"
 
-	"github.com/alex6damian/GoSport/backend/services"
+	"github.com/alex6damian/GoSport/backend/services/rss"
 	"github.com/alex6damian/GoSport/pkg/database"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -23,7 +23,7 @@ func main() {
 	database.DB = db // Set global DB variable
 
 	// Initialize RSS service
-	rssService := services.NewRSSService(database.DB)
+	rssService := rss.NewRSSService(database.DB)
 
 	// Run initial sync on startup
 	log.Println("📰 Running initial RSS sync...")
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Exponential backoff between attempts: 30s, 1m, 2m, 4m ... capped at 30m
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 30 * time.Minute
+)
+
+// Error that retrying cannot fix (e.g., an undecodable upload)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Marks an error as not retryable
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Delay before the next attempt, doubling with every failed attempt
+func backoffDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// Status a failed job moves to: "failed" for permanent errors, "dead" once its attempts are exhausted,
+// otherwise "queued" again, retried after the returned delay
+func failureTransition(job *models.ProcessingJob, jobErr error) (string, time.Duration) {
+	var permErr *permanentError
+	switch {
+	case errors.As(jobErr, &permErr):
+		return "failed", 0
+	case job.Attempts >= job.MaxAttempts:
+		return "dead", 0
+	default:
+		return "queued", backoffDelay(job.Attempts)
+	}
+}
+
+// Schedules a retry for a failed job, or gives up once the error is permanent or attempts are exhausted
+func HandleJobFailure(db *gorm.DB, job *models.ProcessingJob, jobErr error) {
+	entry := fmt.Sprintf("[%s] attempt %d/%d failed: %v\n",
+		time.Now().UTC().Format(time.RFC3339), job.Attempts, job.MaxAttempts, jobErr)
+
+	switch status, delay := failureTransition(job, jobErr); status {
+	case "failed":
+		// Bad input, no point in retrying
+		log.Printf("❌ Job ID %d rejected: %v", job.ID, jobErr)
+		if UpdateJobStatus(db, job, "failed", job.Logs+entry) {
+			UpdateVideoStatus(db, job.VideoID, "failed")
+		}
+
+	case "dead":
+		// Dead-letter: keep the job around for admins to inspect or requeue
+		log.Printf("💀 Job ID %d exhausted its %d attempts, moving to dead", job.ID, job.MaxAttempts)
+		job.NextAttemptAt = nil
+		if UpdateJobStatus(db, job, "dead", job.Logs+entry+"giving up after max attempts\n") {
+			UpdateVideoStatus(db, job.VideoID, "failed")
+		}
+
+	default:
+		nextAttempt := time.Now().Add(delay)
+		log.Printf("🔁 Job ID %d will be retried in %s (attempt %d/%d)", job.ID, delay, job.Attempts+1, job.MaxAttempts)
+		job.NextAttemptAt = &nextAttempt
+		UpdateJobStatus(db, job, "queued", job.Logs+entry)
+	}
+}
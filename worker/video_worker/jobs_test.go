@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, 1 * time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 16 * time.Minute},
+		{7, 30 * time.Minute}, // 32m, capped
+		{8, 30 * time.Minute},
+		{100, 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(tt.attempts); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestFailureTransition(t *testing.T) {
+	transient := errors.New("connection reset")
+	rejected := permanent(errors.New("undecodable upload"))
+
+	tests := []struct {
+		name        string
+		attempts    int
+		maxAttempts int
+		err         error
+		wantStatus  string
+		wantDelay   time.Duration
+	}{
+		{"first failure retried", 1, 3, transient, "queued", 30 * time.Second},
+		{"second failure retried later", 2, 3, transient, "queued", 1 * time.Minute},
+		{"last attempt dead-lettered", 3, 3, transient, "dead", 0},
+		{"past max attempts dead-lettered", 4, 3, transient, "dead", 0},
+		{"single attempt dead-lettered", 1, 1, transient, "dead", 0},
+		{"permanent error fails at once", 1, 3, rejected, "failed", 0},
+		{"wrapped permanent error", 1, 3, fmt.Errorf("probe: %w", rejected), "failed", 0},
+		{"permanent error on last attempt", 3, 3, rejected, "failed", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.ProcessingJob{Attempts: tt.attempts, MaxAttempts: tt.maxAttempts}
+			status, delay := failureTransition(job, tt.err)
+			if status != tt.wantStatus || delay != tt.wantDelay {
+				t.Errorf("failureTransition() = (%s, %s), want (%s, %s)", status, delay, tt.wantStatus, tt.wantDelay)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+// Segment length (seconds) shared by every rendition so keyframes line up across the ladder
+const hlsSegmentDuration = 6
+
+// Rendition describes one variant of the adaptive bitrate ladder
+type Rendition struct {
+	Name         string // 1080p, 720p, etc. (also used as the folder name in MinIO)
+	Width        int    // Landscape box, selectLadder sets the actual output size
+	Height       int
+	VideoBitrate int    // kbps
+	AudioBitrate int    // kbps
+	Profile      string // H.264 profile passed to libx264
+	Level        string // H.264 level passed to libx264
+	VideoCodec   string // RFC 6381 codec string of the video stream (master playlist CODECS)
+}
+
+// RFC 6381 codec string of the AAC-LC audio stream
+const audioCodec = "mp4a.40.2"
+
+// Known renditions, highest quality first
+var availableRenditions = []Rendition{
+	{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: 5000, AudioBitrate: 192, Profile: "high", Level: "4.0", VideoCodec: "avc1.640028"},
+	{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128, Profile: "high", Level: "3.1", VideoCodec: "avc1.64001f"},
+	{Name: "480p", Width: 854, Height: 480, VideoBitrate: 1400, AudioBitrate: 128, Profile: "main", Level: "3.1", VideoCodec: "avc1.4d401f"},
+	{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800, AudioBitrate: 96, Profile: "main", Level: "3.0", VideoCodec: "avc1.4d401e"},
+}
+
+// Default ladder used when HLS_LADDER is not set
+const defaultLadder = "1080p,720p,480p,360p"
+
+// Reads the ladder from HLS_LADDER (comma-separated rendition names, e.g. "720p,360p")
+func loadLadder() []Rendition {
+	value := os.Getenv("HLS_LADDER")
+	if value == "" {
+		value = defaultLadder
+	}
+
+	var ladder []Rendition
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, r := range availableRenditions {
+			if r.Name == name {
+				ladder = append(ladder, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("⚠️  Unknown rendition %q in HLS_LADDER, skipping", name)
+		}
+	}
+
+	// Never run with an empty ladder
+	if len(ladder) == 0 {
+		log.Printf("⚠️  HLS_LADDER has no valid renditions, falling back to %s", defaultLadder)
+		ladder = make([]Rendition, len(availableRenditions))
+		copy(ladder, availableRenditions)
+	}
+
+	return ladder
+}
+
+// Drops renditions whose short side exceeds the source's (no upscaling), always keeping at least the smallest one,
+// and sizes each kept rendition to the source aspect ratio
+func selectLadder(ladder []Rendition, info *models.MediaInfo) []Rendition {
+	sourceWidth, sourceHeight := displaySize(info)
+	shortSide := min(sourceWidth, sourceHeight)
+
+	var selected []Rendition
+	for _, r := range ladder {
+		if r.Height <= shortSide {
+			selected = append(selected, fitRendition(r, sourceWidth, sourceHeight))
+		}
+	}
+
+	if len(selected) == 0 {
+		selected = append(selected, fitRendition(ladder[len(ladder)-1], sourceWidth, sourceHeight))
+	}
+
+	return selected
+}
+
+// Fits the source into the rendition box turned to the source orientation (portrait sources get a portrait box),
+// keeping the source aspect ratio and never exceeding the source size. Sizes are even, as libx264 needs.
+func fitRendition(r Rendition, sourceWidth, sourceHeight int) Rendition {
+	boxWidth, boxHeight := r.Width, r.Height
+	if sourceHeight > sourceWidth {
+		boxWidth, boxHeight = r.Height, r.Width
+	}
+
+	scale := min(float64(boxWidth)/float64(sourceWidth), float64(boxHeight)/float64(sourceHeight), 1)
+	r.Width = evenSize(float64(sourceWidth) * scale)
+	r.Height = evenSize(float64(sourceHeight) * scale)
+	return r
+}
+
+// Rounds a frame dimension down to an even number of pixels (at least 2)
+func evenSize(size float64) int {
+	return max(int(math.Round(size))&^1, 2)
+}
+
+// Peak and average bandwidth (bits/s) advertised in the master playlist
+func (r Rendition) Bandwidth(hasAudio bool) (int, int) {
+	audio := 0
+	if hasAudio {
+		audio = r.AudioBitrate
+	}
+
+	maxRate := r.VideoBitrate * 107 / 100 // Matches -maxrate passed to ffmpeg
+	return (maxRate + audio) * 1000, (r.VideoBitrate + audio) * 1000
+}
+
+// CODECS attribute of the variant
+func (r Rendition) Codecs(hasAudio bool) string {
+	if hasAudio {
+		return r.VideoCodec + "," + audioCodec
+	}
+	return r.VideoCodec
+}
+
+// Transcodes the input into a single HLS rendition inside outputDir/<rendition>/
+// (onTime receives the media time transcoded so far, in seconds)
+func transcodeRendition(ctx context.Context, inputPath, outputDir string, r Rendition, onTime func(seconds float64)) error {
+	renditionDir := filepath.Join(outputDir, r.Name)
+	if err := os.MkdirAll(renditionDir, 0755); err != nil {
+		return fmt.Errorf("failed to create rendition directory: %v", err)
+	}
+
+	// The rendition already has the source aspect ratio (see fitRendition), no padding needed
+	scaleFilter := fmt.Sprintf("scale=%d:%d,setsar=1", r.Width, r.Height)
+
+	cmd := exec.CommandContext(ctx,
+		"ffmpeg",
+		"-y",
+		"-nostats",            // No progress line on stderr...
+		"-progress", "pipe:1", // ...machine-readable progress on stdout instead
+		"-i", inputPath, // Input file
+		"-map", "0:v:0", // First video stream
+		"-map", "0:a:0?", // First audio stream (if any)
+		"-vf", scaleFilter,
+		"-c:v", "libx264", // H.264 video
+		"-preset", "veryfast",
+		"-profile:v", r.Profile,
+		"-level", r.Level,
+		"-b:v", strconv.Itoa(r.VideoBitrate)+"k",
+		"-maxrate", strconv.Itoa(r.VideoBitrate*107/100)+"k",
+		"-bufsize", strconv.Itoa(r.VideoBitrate*3/2)+"k",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentDuration), // Keyframe at every segment boundary
+		"-sc_threshold", "0", // No extra keyframes on scene cuts
+		"-c:a", "aac", // AAC audio
+		"-b:a", strconv.Itoa(r.AudioBitrate)+"k",
+		"-ac", "2",
+		"-start_number", "0", // Start segment numbering at 0
+		"-hls_time", strconv.Itoa(hlsSegmentDuration),
+		"-hls_list_size", "0", // Include all segments in playlist
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(renditionDir, "segment_%03d.ts"),
+		"-f", "hls", // Output format is HLS
+		filepath.Join(renditionDir, "index.m3u8"), // Media playlist for this rendition
+	)
+
+	// Catch FFmpeg output for logging, progress comes on stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to read ffmpeg progress: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg for %s: %v", r.Name, err)
+	}
+	parseFFmpegProgress(stdout, onTime)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed for %s: %v, output: %s", r.Name, err, stderr.String())
+	}
+
+	return nil
+}
+
+// Writes the master playlist that references every rendition's media playlist
+func writeMasterPlaylist(path string, ladder []Rendition, hasAudio bool) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:3\n")
+
+	for _, r := range ladder {
+		peak, average := r.Bandwidth(hasAudio)
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\"\n",
+			peak, average, r.Width, r.Height, r.Codecs(hasAudio))
+		fmt.Fprintf(&b, "%s/index.m3u8\n", r.Name)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
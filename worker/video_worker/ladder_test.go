@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+)
+
+func renditionNames(ladder []Rendition) []string {
+	names := make([]string, len(ladder))
+	for i, r := range ladder {
+		names[i] = r.Name
+	}
+	return names
+}
+
+func TestLoadLadder(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"default", "", []string{"1080p", "720p", "480p", "360p"}},
+		{"subset", "720p, 360p", []string{"720p", "360p"}},
+		{"unknown entry skipped", "720p,999p,,360p", []string{"720p", "360p"}},
+		{"only invalid entries", "4k,bogus", []string{"1080p", "720p", "480p", "360p"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HLS_LADDER", tt.value)
+			if got := renditionNames(loadLadder()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadLadder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectLadder(t *testing.T) {
+	type size struct{ name, res string }
+
+	tests := []struct {
+		name string
+		info models.MediaInfo
+		want []size
+	}{
+		{
+			name: "1080p landscape",
+			info: models.MediaInfo{Width: 1920, Height: 1080},
+			want: []size{{"1080p", "1920x1080"}, {"720p", "1280x720"}, {"480p", "852x480"}, {"360p", "640x360"}},
+		},
+		{
+			name: "720p drops 1080p",
+			info: models.MediaInfo{Width: 1280, Height: 720},
+			want: []size{{"720p", "1280x720"}, {"480p", "852x480"}, {"360p", "640x360"}},
+		},
+		{
+			name: "4:3 keeps its aspect ratio",
+			info: models.MediaInfo{Width: 640, Height: 480},
+			want: []size{{"480p", "640x480"}, {"360p", "480x360"}},
+		},
+		{
+			name: "portrait",
+			info: models.MediaInfo{Width: 1080, Height: 1920},
+			want: []size{{"1080p", "1080x1920"}, {"720p", "720x1280"}, {"480p", "480x852"}, {"360p", "360x640"}},
+		},
+		{
+			name: "portrait stored rotated",
+			info: models.MediaInfo{Width: 1280, Height: 720, Rotation: 90},
+			want: []size{{"720p", "720x1280"}, {"480p", "480x852"}, {"360p", "360x640"}},
+		},
+		{
+			name: "smaller than the lowest rung",
+			info: models.MediaInfo{Width: 320, Height: 180},
+			want: []size{{"360p", "320x180"}},
+		},
+		{
+			name: "odd source size",
+			info: models.MediaInfo{Width: 321, Height: 241},
+			want: []size{{"360p", "320x240"}},
+		},
+		{
+			name: "ultrawide fits the box width",
+			info: models.MediaInfo{Width: 2560, Height: 1080},
+			want: []size{{"1080p", "1920x810"}, {"720p", "1280x540"}, {"480p", "854x360"}, {"360p", "640x270"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ladder := selectLadder(availableRenditions, &tt.info)
+
+			got := make([]size, len(ladder))
+			for i, r := range ladder {
+				got[i] = size{r.Name, fmt.Sprintf("%dx%d", r.Width, r.Height)}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectLadder() = %v, want %v", got, tt.want)
+			}
+
+			// Never larger than the source, always even
+			sourceWidth, sourceHeight := displaySize(&tt.info)
+			for _, r := range ladder {
+				if r.Width > sourceWidth || r.Height > sourceHeight {
+					t.Errorf("%s is %dx%d, larger than the %dx%d source", r.Name, r.Width, r.Height, sourceWidth, sourceHeight)
+				}
+				if r.Width%2 != 0 || r.Height%2 != 0 {
+					t.Errorf("%s has an odd size %dx%d", r.Name, r.Width, r.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMasterPlaylist(t *testing.T) {
+	ladder := selectLadder(loadLadderFrom(t, "720p,360p"), &models.MediaInfo{Width: 1080, Height: 1920})
+
+	tests := []struct {
+		name     string
+		hasAudio bool
+		want     string
+	}{
+		{
+			name:     "with audio",
+			hasAudio: true,
+			want: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=3124000,AVERAGE-BANDWIDTH=2928000,RESOLUTION=720x1280,CODECS=\"avc1.64001f,mp4a.40.2\"\n" +
+				"720p/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=952000,AVERAGE-BANDWIDTH=896000,RESOLUTION=360x640,CODECS=\"avc1.4d401e,mp4a.40.2\"\n" +
+				"360p/index.m3u8\n",
+		},
+		{
+			name:     "video only",
+			hasAudio: false,
+			want: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2996000,AVERAGE-BANDWIDTH=2800000,RESOLUTION=720x1280,CODECS=\"avc1.64001f\"\n" +
+				"720p/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=856000,AVERAGE-BANDWIDTH=800000,RESOLUTION=360x640,CODECS=\"avc1.4d401e\"\n" +
+				"360p/index.m3u8\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "master.m3u8")
+			if err := writeMasterPlaylist(path, ladder, tt.hasAudio); err != nil {
+				t.Fatalf("writeMasterPlaylist() error = %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("master playlist =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func loadLadderFrom(t *testing.T, value string) []Rendition {
+	t.Helper()
+	t.Setenv("HLS_LADDER", value)
+	return loadLadder()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	leaseDuration     = 2 * time.Minute  // A job whose lease is older than this is considered abandoned
+	heartbeatInterval = 30 * time.Second // How often a busy worker extends its lease
+	reaperInterval    = 1 * time.Minute  // How often expired leases are requeued
+)
+
+// Identifies this worker process in processing_jobs.locked_by (hostname-pid)
+var workerID = func() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}()
+
+// Extends the job lease every heartbeatInterval until ctx is done, cancels the job if the lease was lost
+func keepLeaseAlive(ctx context.Context, cancel context.CancelFunc, db *gorm.DB, job *models.ProcessingJob) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			result := db.Model(&models.ProcessingJob{}).
+				Where("id = ? AND status = ? AND locked_by = ?", job.ID, "processing", workerID).
+				Updates(map[string]interface{}{
+					"heartbeat_at":     now,
+					"lease_expires_at": now.Add(leaseDuration),
+				})
+
+			if result.Error != nil {
+				// Transient DB error: keep working, the next heartbeat may succeed before the lease expires
+				log.Printf("⚠️  Heartbeat failed for job ID %d: %v", job.ID, result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				log.Printf("⚠️  Job ID %d is no longer leased by %s, aborting", job.ID, workerID)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// Checks that this worker still holds the lease on the job
+func ownsJob(db *gorm.DB, job *models.ProcessingJob) bool {
+	var count int64
+	err := db.Model(&models.ProcessingJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, "processing", workerID).
+		Count(&count).Error
+	if err != nil {
+		// Can't tell, assume we still own it so the result is not silently dropped
+		log.Printf("⚠️  Failed to check lease of job ID %d: %v", job.ID, err)
+		return true
+	}
+	return count > 0
+}
+
+// Periodically returns jobs with an expired lease to the queue, until ctx is done
+func runReaper(ctx context.Context, db *gorm.DB) {
+	for {
+		reaped, err := reapExpiredLeases(db)
+		if err != nil {
+			log.Printf("Error reaping expired leases: %v", err)
+		} else if reaped > 0 {
+			log.Printf("🧹 Requeued %d job(s) abandoned by crashed workers", reaped)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reaperInterval):
+		}
+	}
+}
+
+// Finds "processing" jobs whose worker stopped heartbeating and treats them as a failed attempt
+func reapExpiredLeases(db *gorm.DB) (int, error) {
+	reaped := 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var jobs []models.ProcessingJob
+		// Jobs claimed before leases existed have no lease_expires_at, fall back to updated_at
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", "processing").
+			Where("lease_expires_at < ? OR (lease_expires_at IS NULL AND updated_at < ?)", now, now.Add(-leaseDuration)).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+
+		for i := range jobs {
+			job := &jobs[i]
+			log.Printf("Lease of job ID %d (worker %q) expired, requeueing", job.ID, job.LockedBy)
+
+			// Counts as a failed attempt, so a job that keeps crashing workers ends up dead
+			HandleJobFailure(tx, job, errors.New("lease expired: worker "+job.LockedBy+" stopped heartbeating"))
+			reaped++
+		}
+
+		return nil
+	})
+
+	return reaped, err
+}
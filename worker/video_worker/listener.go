@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// Delay before reconnecting a dropped LISTEN connection
+const listenReconnectDelay = 5 * time.Second
+
+// LISTENs for new jobs on a dedicated connection and wakes an idle executor for each notification
+func listenForJobs(ctx context.Context, dsn string, wakeup chan<- struct{}) {
+	for {
+		err := listen(ctx, dsn, wakeup)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("⚠️  Job listener disconnected: %v. Reconnecting in %s (polling meanwhile)...", err, listenReconnectDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenReconnectDelay):
+		}
+	}
+}
+
+// Holds one LISTEN connection until it fails or ctx is done
+func listen(ctx context.Context, dsn string, wakeup chan<- struct{}) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{database.JobsChannel}.Sanitize()); err != nil {
+		return err
+	}
+	log.Printf("👂 Listening for new jobs on channel %q", database.JobsChannel)
+
+	// Jobs may have been queued while we were not listening
+	signalWakeup(wakeup)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("🔔 Job ID %s queued", notification.Payload)
+		signalWakeup(wakeup)
+	}
+}
+
+// Non-blocking send: if every slot is taken, enough executors are already about to look for work
+func signalWakeup(wakeup chan<- struct{}) {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
@@ -4,19 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
-	"github.com/alex6damian/GoSport/pkg/config"
 	"github.com/alex6damian/GoSport/pkg/database"
 	"github.com/alex6damian/GoSport/pkg/models"
-	"github.com/minio/minio-go/v7"
+	"github.com/alex6damian/GoSport/pkg/storage"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func main() {
@@ -29,42 +32,28 @@ func main() {
 
 	database.DB = db // Set global DB variable
 
-	// Initialize MinIO client and bucket
-	if err := config.InitMinio(); err != nil {
-		log.Fatalf("⚠️  WARNING: Failed to initialize MinIO: %v", err)
+	// Initialize storage (MinIO bucket or local directory shared with the API, see STORAGE_DRIVER)
+	if err := storage.Init(); err != nil {
+		log.Fatalf("⚠️  WARNING: Failed to initialize storage: %v", err)
 	}
 
-	log.Println("🚀 Worker started. Looking for jobs...")
+	// Stop claiming new jobs on SIGTERM (docker stop) or Ctrl+C
+	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	// Worker loop
-	for {
-		job, err := findAndLockJob(database.DB)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// No pending jobs, sleep and retry
-				log.Println("No pending jobs. Waiting...")
-				time.Sleep(10 * time.Second)
-				continue
-			}
-			log.Printf("Error finding job: %v. Retrying...", err)
-			time.Sleep(10 * time.Second)
-			continue
-		}
+	log.Printf("🚀 Worker %s started. Looking for jobs...", workerID)
 
-		// Found a job, process it
-		log.Printf("Processing job ID %d for video ID %d", job.ID, job.VideoID)
+	// Requeue jobs abandoned by crashed workers (now and periodically)
+	go runReaper(shutdownCtx, database.DB)
 
-		err = processJob(database.DB, job)
-		if err != nil {
-			log.Printf("Failed to process job ID %d: %v", job.ID, err)
-			UpdateJobStatus(database.DB, job, "failed", err.Error())
-			UpdateVideoStatus(database.DB, job.VideoID, "failed")
-		} else {
-			log.Printf("Successfully processed job ID %d", job.ID)
-			UpdateJobStatus(database.DB, job, "completed", "")
-			// Video status is updated in processJob
-		}
-	}
+	// Wake idle executors as soon as the API queues a job (one slot per executor)
+	concurrency := loadConcurrency()
+	wakeup := make(chan struct{}, concurrency)
+	go listenForJobs(shutdownCtx, dsn, wakeup)
+
+	// Process jobs with a bounded pool of executors until shutdown
+	runPool(shutdownCtx, database.DB, wakeup, concurrency, loadShutdownWait())
+	log.Println("👋 Worker stopped")
 }
 
 // Finds the next pending job and locks it for processing
@@ -72,15 +61,29 @@ func findAndLockJob(db *gorm.DB) (*models.ProcessingJob, error) {
 	var job models.ProcessingJob
 
 	err := db.Transaction(func(tx *gorm.DB) error {
-		// Find "queued" job and lock it
-		if err := tx.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+		// Find "queued" job whose backoff has elapsed and lock it
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
 			Where("status = ?", "queued").
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+			Order("id").
 			First(&job).Error; err != nil {
 			return err
 		}
 
-		// Update status to "processing"
+		// Update status to "processing", count the attempt and take the lease
+		leaseExpiresAt := time.Now().Add(leaseDuration)
 		job.Status = "processing"
+		job.Attempts++
+		job.NextAttemptAt = nil
+		job.LockedBy = workerID
+		job.LeaseExpiresAt = &leaseExpiresAt
+
+		// Fresh progress for this attempt
+		startedAt := time.Now()
+		job.StartedAt = &startedAt
+		job.Stage = ""
+		job.Progress = 0
+		job.ETASeconds = nil
 		if err := tx.Save(&job).Error; err != nil {
 			return err
 		}
@@ -91,20 +94,24 @@ func findAndLockJob(db *gorm.DB) (*models.ProcessingJob, error) {
 	if err != nil {
 		return nil, err
 	}
+	notifyProgress(db, job.VideoID)
 
 	return &job, nil
 }
 
-func processJob(db *gorm.DB, job *models.ProcessingJob) error {
+func processJob(ctx context.Context, db *gorm.DB, job *models.ProcessingJob) error {
 	log.Printf("Starting processing for job ID %d...", job.ID)
 
 	// Getting video details from DB (MinioKey)
 	var video models.Video
 	if err := db.First(&video, job.VideoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return permanent(fmt.Errorf("video with ID %d no longer exists", job.VideoID))
+		}
 		return fmt.Errorf("failed to fetch video details: %v", err)
 	}
 	if video.MinioKey == "" {
-		return fmt.Errorf("video with ID %d has no MinIO key", video.ID)
+		return permanent(fmt.Errorf("video with ID %d has no MinIO key", video.ID))
 	}
 
 	// Creating temporary directory for processing (base temp dir + unique subdir for this job)
@@ -124,66 +131,145 @@ func processJob(db *gorm.DB, job *models.ProcessingJob) error {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
-	// Download video from MinIO to local temp directory
-	log.Printf("Downloading video from MinIO: %s to %s", video.MinioKey, localInputPath)
-	bucketName := os.Getenv("MINIO_BUCKET_NAME")
-	err = config.MinioClient.FGetObject(context.Background(), bucketName, video.MinioKey, localInputPath, minio.GetObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to download video from MinIO: %v", err)
+	// Stage, percentage and ETA are persisted on the job for the owner to poll
+	progress := newProgressReporter(db, job)
+
+	// Download video from storage to local temp directory
+	progress.Stage("download")
+	log.Printf("Downloading video from storage: %s to %s", video.MinioKey, localInputPath)
+	if err := downloadObject(ctx, video.MinioKey, localInputPath, video.FileSize, progress.Update); err != nil {
+		return fmt.Errorf("failed to download video from storage: %v", err)
 	}
 	log.Println("Download complete. Starting processing...")
 
-	// Run FFmpeg to convert video to HLS format
-	log.Println("Starting FFmpeg...")
-	hlsMasterPlaylist := "master.m3u8"
-	cmd := exec.Command(
-		"ffmpeg",
-		"-i", localInputPath, // Input file
-		"-codec:", "copy", // Copy codecs (no re-encoding for speed)
-		"-start_number", "0", // Start segment numbering at 0
-		"-hls_time", "10", // Each segment is 10 seconds
-		"-hls_list_size", "0", // Include all segments in playlist
-		"-f", "hls", // Output format is HLS
-		filepath.Join(localOutputPath, hlsMasterPlaylist), // Master playlist output path
-	)
-
-	// Catch FFmpeg output for logging
-	output, err := cmd.CombinedOutput()
+	// Probe the source before spending CPU on it (rejects undecodable uploads early)
+	progress.Stage("probe")
+	log.Println("Probing media info...")
+	mediaInfo, err := probeMedia(ctx, localInputPath)
 	if err != nil {
-		return fmt.Errorf("ffmpeg failed: %v, output: %s", err, string(output))
+		return err
+	}
+	if err := SaveMediaInfo(db, video.ID, mediaInfo); err != nil {
+		return fmt.Errorf("failed to save media info: %v", err)
+	}
+	log.Printf("Source: %s %dx%d @ %.2f fps, %.1fs, audio tracks: %d",
+		mediaInfo.VideoCodec, mediaInfo.Width, mediaInfo.Height, mediaInfo.FrameRate, mediaInfo.Duration, mediaInfo.AudioTracks)
+
+	// Transcode every rendition of the ladder (never above the source resolution)
+	progress.Stage("transcode")
+	ladder := selectLadder(loadLadder(), mediaInfo)
+	for i, rendition := range ladder {
+		log.Printf("Starting FFmpeg for rendition %s...", rendition.Name)
+
+		// Each rendition is an equal share of the transcode stage
+		done := float64(i)
+		onTime := func(seconds float64) {
+			progress.Update((done + seconds/mediaInfo.Duration) / float64(len(ladder)))
+		}
+		if err := transcodeRendition(ctx, localInputPath, localOutputPath, rendition, onTime); err != nil {
+			return err
+		}
 	}
 	log.Println("FFmpeg processing complete.")
 
-	// Upload HLS output back to MinIO
-	log.Println("Uploading HLS files to MinIO...")
-	hlsRemotePath := fmt.Sprintf("videos/hls/%d/", video.ID) // e.g., videos/hls/123/
+	// Write master playlist referencing every rendition
+	hlsMasterPlaylist := "master.m3u8"
+	if err := writeMasterPlaylist(filepath.Join(localOutputPath, hlsMasterPlaylist), ladder, mediaInfo.AudioTracks > 0); err != nil {
+		return fmt.Errorf("failed to write master playlist: %v", err)
+	}
 
-	// Search for all generated HLS files (master playlist + segments)
-	files, err := filepath.Glob(filepath.Join(localOutputPath, "*"))
+	// Generate poster, thumbnail candidates and scrub preview sprites (artwork is optional, never fails the job)
+	progress.Stage("thumbnails")
+	log.Println("Generating thumbnails...")
+	artworkPath := filepath.Join(jobTempDir, "artwork")
+	thumbnails, err := generateThumbnails(ctx, localInputPath, artworkPath, mediaInfo.Duration)
 	if err != nil {
-		return fmt.Errorf("failed to list HLS output files: %v", err)
+		log.Printf("⚠️  Thumbnail generation failed for video ID %d: %v", video.ID, err)
 	}
 
-	for _, file := range files {
-		objectName := hlsRemotePath + filepath.Base(file) // e.g., videos/hls/123/master.m3u8 or videos/hls/123/segment0.ts
-		contentType := "application/vnd.apple.mpegurl"    // Default content type for HLS files
-		if filepath.Ext(file) == ".ts" {
-			contentType = "video/mp2t" // Content type for TS segments
-		}
+	// Upload HLS output back to storage
+	progress.Stage("upload")
+	if err := checkStillWanted(db, job); err != nil {
+		return err
+	}
+	log.Println("Uploading HLS files to storage...")
+	hlsRemotePath := fmt.Sprintf("videos/hls/%d/", video.ID) // e.g., videos/hls/123/
+	// Master playlist + one folder per rendition
+	if err := uploadDirectory(ctx, localOutputPath, hlsRemotePath, progress.Update); err != nil {
+		return err
+	}
+	log.Println("HLS upload complete")
 
-		_, err = config.MinioClient.FPutObject(context.Background(), bucketName, objectName,
-			file, minio.PutObjectOptions{ContentType: contentType})
-		if err != nil {
-			return fmt.Errorf("failed to upload HLS file %s to MinIO: %v", objectName, err)
+	if thumbnails != nil {
+		if err := checkStillWanted(db, job); err != nil {
+			return err
+		}
+		artworkRemotePath := fmt.Sprintf("videos/thumbnails/%d/", video.ID) // e.g., videos/thumbnails/123/
+		if err := uploadDirectory(ctx, artworkPath, artworkRemotePath, nil); err != nil {
+			log.Printf("⚠️  Thumbnail upload failed for video ID %d: %v", video.ID, err)
+		} else if err := UpdateVideoArtwork(db, video.ID, artworkRemotePath, thumbnails); err != nil {
+			log.Printf("⚠️  Failed to save thumbnails for video ID %d: %v", video.ID, err)
+		} else {
+			log.Println("Thumbnails uploaded")
 		}
 	}
-	log.Println("HLS upload complete")
 
 	// Update video to "ready" status and set HLS path to the master playlist URL
 	finalHLSPath := hlsRemotePath + hlsMasterPlaylist // e.g., videos/hls/123/master.m3u8
 	return UpdateVideoSuccess(db, job.VideoID, finalHLSPath)
 }
 
+// Checks, before uploading, that the video was not deleted meanwhile: its cleanup only waits for the lease
+// of this job, files uploaded once the job is gone would never be removed
+func checkStillWanted(db *gorm.DB, job *models.ProcessingJob) error {
+	if !ownsJob(db, job) {
+		return permanent(fmt.Errorf("job ID %d was removed or taken over, not uploading", job.ID))
+	}
+
+	err := db.Select("id").First(&models.Video{}, job.VideoID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return permanent(fmt.Errorf("video with ID %d was deleted, not uploading", job.VideoID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check video ID %d: %v", job.VideoID, err)
+	}
+
+	return nil
+}
+
+// Downloads an object to a local file, reporting the fraction received (size is the expected object size)
+func downloadObject(ctx context.Context, objectName, localPath string, size int64, onProgress func(fraction float64)) error {
+	object, err := storage.Default.Get(ctx, objectName)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Retrying won't bring the upload back
+		return permanent(fmt.Errorf("original file %s is missing from storage", objectName))
+	}
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := &countingWriter{w: file, onWrite: func(written int64) {
+		if size > 0 {
+			onProgress(float64(written) / float64(size))
+		}
+	}}
+	if _, err := io.Copy(writer, object); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
 func UpdateVideoSuccess(db *gorm.DB, videoID uint, hlsPath string) error {
 	return db.Model(&models.Video{}).
 		Where("id = ?", videoID).
@@ -193,11 +279,143 @@ func UpdateVideoSuccess(db *gorm.DB, videoID uint, hlsPath string) error {
 		}).Error
 }
 
-// Update job status and logs
-func UpdateJobStatus(db *gorm.DB, job *models.ProcessingJob, status string, logs string) {
+// Stores the artwork object keys on the video row
+func UpdateVideoArtwork(db *gorm.DB, videoID uint, remotePath string, thumbnails *ThumbnailSet) error {
+	candidates := make([]string, 0, len(thumbnails.Candidates))
+	for _, candidate := range thumbnails.Candidates {
+		candidates = append(candidates, remotePath+candidate)
+	}
+
+	return db.Model(&models.Video{}).
+		Where("id = ?", videoID).
+		Updates(models.Video{
+			Thumbnail:           remotePath + thumbnails.Poster,
+			ThumbnailCandidates: candidates,
+			PreviewVTT:          remotePath + thumbnails.PreviewVTT,
+		}).Error
+}
+
+// Uploads every file below localDir to storage, keeping the relative layout under remotePrefix
+// (onProgress, if set, receives the fraction of files uploaded)
+func uploadDirectory(ctx context.Context, localDir, remotePrefix string, onProgress func(fraction float64)) error {
+	var files []string
+	err := filepath.WalkDir(localDir, func(file string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !d.IsDir() {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files in %s: %v", localDir, err)
+	}
+
+	for i, file := range files {
+		relPath, err := filepath.Rel(localDir, file)
+		if err != nil {
+			return err
+		}
+
+		objectName := remotePrefix + filepath.ToSlash(relPath) // e.g., videos/hls/123/720p/segment_000.ts
+		if err := uploadFile(ctx, file, objectName); err != nil {
+			return fmt.Errorf("failed to upload file %s: %v", objectName, err)
+		}
+
+		if onProgress != nil {
+			onProgress(float64(i+1) / float64(len(files)))
+		}
+	}
+
+	return nil
+}
+
+// Uploads a single local file
+func uploadFile(ctx context.Context, file, objectName string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return storage.Default.Put(ctx, objectName, f, info.Size(), contentTypeFor(file))
+}
+
+// Content type of generated files, based on their extension
+func contentTypeFor(file string) string {
+	switch filepath.Ext(file) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t" // Content type for TS segments
+	case ".jpg":
+		return "image/jpeg"
+	case ".vtt":
+		return "text/vtt"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Update job status and logs (releases the lease once the job leaves "processing").
+// Only the columns of the transition are written, progress and heartbeats are updated concurrently,
+// and nothing is written unless the job is still processing under the lease it was read with
+// (reaped or removed meanwhile). Reports whether the job was updated.
+func UpdateJobStatus(db *gorm.DB, job *models.ProcessingJob, status string, logs string) bool {
+	owner := job.LockedBy
+	updates := map[string]interface{}{
+		"status":          status,
+		"logs":            logs,
+		"next_attempt_at": job.NextAttemptAt,
+	}
+	if status != "processing" {
+		updates["locked_by"] = ""
+		updates["lease_expires_at"] = nil
+		updates["eta_seconds"] = nil
+	}
+	if status == "completed" {
+		updates["stage"] = ""
+		updates["progress"] = 100
+	}
+
+	result := db.Model(&models.ProcessingJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, "processing", owner).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("⚠️  Failed to update job ID %d to %s: %v", job.ID, status, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("⚠️  Job ID %d is no longer processing under %s, %s not recorded", job.ID, owner, status)
+		return false
+	}
+
 	job.Status = status
 	job.Logs = logs
-	db.Save(job)
+	if status != "processing" {
+		job.LockedBy = ""
+		job.LeaseExpiresAt = nil
+		job.ETASeconds = nil
+	}
+	if status == "completed" {
+		job.Stage = ""
+		job.Progress = 100
+	}
+	notifyProgress(db, job.VideoID)
+	return true
+}
+
+// Pushes a status/progress change to the API's processing event streams (they poll slowly if this is missed)
+func notifyProgress(db *gorm.DB, videoID uint) {
+	if err := database.NotifyJobProgress(db, videoID); err != nil {
+		log.Printf("⚠️  Failed to notify progress of video ID %d: %v", videoID, err)
+	}
 }
 
 // Update video status
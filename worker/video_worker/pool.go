@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"gorm.io/gorm"
+)
+
+const (
+	pollInterval        = 30 * time.Second // Fallback polling (executors are normally woken by LISTEN/NOTIFY)
+	defaultConcurrency  = 2
+	defaultShutdownWait = 60 * time.Second // In-flight jobs get this long to finish on SIGTERM
+)
+
+// Number of jobs processed in parallel (WORKER_CONCURRENCY)
+func loadConcurrency() int {
+	n, err := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY"))
+	if err != nil || n < 1 {
+		return defaultConcurrency
+	}
+	return n
+}
+
+// Time in-flight jobs may keep running after SIGTERM before they are released (WORKER_SHUTDOWN_TIMEOUT, seconds)
+func loadShutdownWait() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("WORKER_SHUTDOWN_TIMEOUT"))
+	if err != nil || seconds < 0 {
+		return defaultShutdownWait
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Runs N executors until shutdownCtx is done, then waits for (or releases) the in-flight jobs
+func runPool(shutdownCtx context.Context, db *gorm.DB, wakeup <-chan struct{}, concurrency int, shutdownWait time.Duration) {
+	// Jobs run on their own context so SIGTERM stops claiming without killing ffmpeg right away
+	jobsCtx, abortJobs := context.WithCancel(context.Background())
+	defer abortJobs()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= concurrency; i++ {
+		wg.Add(1)
+		go func(executorID int) {
+			defer wg.Done()
+			runExecutor(shutdownCtx, jobsCtx, db, wakeup, executorID)
+		}(i)
+	}
+	log.Printf("👷 Started %d executor(s)", concurrency)
+
+	<-shutdownCtx.Done()
+	log.Printf("🛑 Shutdown requested, waiting up to %s for in-flight jobs...", shutdownWait)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("✅ All in-flight jobs finished")
+	case <-time.After(shutdownWait):
+		log.Println("⏹️  Shutdown timeout reached, releasing in-flight jobs back to the queue")
+		abortJobs()
+		<-done
+	}
+}
+
+// Claims and processes jobs one at a time until shutdownCtx is done
+func runExecutor(shutdownCtx, jobsCtx context.Context, db *gorm.DB, wakeup <-chan struct{}, executorID int) {
+	for {
+		if shutdownCtx.Err() != nil {
+			return
+		}
+
+		job, err := findAndLockJob(db)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// No pending jobs, wait for a notification (or the next poll)
+				log.Printf("[executor %d] No pending jobs. Waiting...", executorID)
+			} else {
+				log.Printf("[executor %d] Error finding job: %v. Retrying...", executorID, err)
+			}
+
+			select {
+			case <-shutdownCtx.Done():
+				return
+			case <-wakeup:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		// Found a job, process it
+		log.Printf("[executor %d] Processing job ID %d for video ID %d", executorID, job.ID, job.VideoID)
+		executeJob(jobsCtx, db, job)
+	}
+}
+
+// Processes a claimed job and records the outcome
+func executeJob(jobsCtx context.Context, db *gorm.DB, job *models.ProcessingJob) {
+	// Keep the lease alive while processing (aborts the job if another worker took it over)
+	ctx, cancel := context.WithCancel(jobsCtx)
+	go keepLeaseAlive(ctx, cancel, db, job)
+
+	err := processJob(ctx, db, job)
+	cancel()
+
+	// Shutdown timeout hit: hand the job back untouched instead of counting a failure
+	if jobsCtx.Err() != nil {
+		releaseJob(db, job)
+		return
+	}
+
+	if !ownsJob(db, job) {
+		log.Printf("⚠️  Lease on job ID %d was lost, leaving its status to the new owner", job.ID)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to process job ID %d (attempt %d/%d): %v", job.ID, job.Attempts, job.MaxAttempts, err)
+		// Retries with backoff, or moves the job to failed/dead
+		HandleJobFailure(db, job, err)
+	} else {
+		log.Printf("Successfully processed job ID %d", job.ID)
+		UpdateJobStatus(db, job, "completed", job.Logs)
+		// Video status is updated in processJob
+	}
+}
+
+// Returns an interrupted job to the queue without consuming an attempt
+func releaseJob(db *gorm.DB, job *models.ProcessingJob) {
+	entry := "[" + time.Now().UTC().Format(time.RFC3339) + "] released by " + workerID + " on shutdown\n"
+
+	result := db.Model(&models.ProcessingJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, "processing", workerID).
+		Updates(map[string]interface{}{
+			"status":           "queued",
+			"attempts":         gorm.Expr("GREATEST(attempts - 1, 0)"),
+			"locked_by":        "",
+			"lease_expires_at": nil,
+			"next_attempt_at":  nil,
+			"logs":             job.Logs + entry,
+		})
+	if result.Error != nil {
+		// The reaper will requeue it once the lease expires
+		log.Printf("⚠️  Failed to release job ID %d: %v", job.ID, result.Error)
+		return
+	}
+
+	log.Printf("↩️  Released job ID %d back to the queue", job.ID)
+	notifyProgress(db, job.VideoID)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Subset of the ffprobe JSON output we care about
+type ffprobeOutput struct {
+	Streams []ffprobeStream `json:"streams"`
+	Format  struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+type ffprobeStream struct {
+	CodecType    string            `json:"codec_type"` // video, audio, subtitle, data
+	CodecName    string            `json:"codec_name"`
+	Profile      string            `json:"profile"`
+	Width        int               `json:"width"`
+	Height       int               `json:"height"`
+	PixFmt       string            `json:"pix_fmt"`
+	AvgFrameRate string            `json:"avg_frame_rate"` // e.g., "30000/1001"
+	Channels     int               `json:"channels"`
+	SampleRate   string            `json:"sample_rate"`
+	Duration     string            `json:"duration"`
+	Tags         map[string]string `json:"tags"`
+	Disposition  struct {
+		AttachedPic int `json:"attached_pic"` // cover art is reported as a video stream
+	} `json:"disposition"`
+	SideDataList []struct {
+		Rotation int `json:"rotation"`
+	} `json:"side_data_list"`
+}
+
+// Runs ffprobe on the input and extracts the source media information
+func probeMedia(ctx context.Context, inputPath string) (*models.MediaInfo, error) {
+	cmd := exec.CommandContext(ctx,
+		"ffprobe",
+		"-v", "error", // Only report real errors
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		inputPath,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, permanent(fmt.Errorf("uploaded file could not be decoded (unsupported or corrupted video): %s",
+			strings.TrimSpace(stderr.String())))
+	}
+
+	var output ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+
+	info := &models.MediaInfo{
+		FormatName: output.Format.FormatName,
+		Duration:   parseFloat(output.Format.Duration),
+		BitRate:    int64(parseFloat(output.Format.BitRate)),
+	}
+
+	hasVideo := false
+	for _, stream := range output.Streams {
+		switch stream.CodecType {
+		case "video":
+			// Keep the first real video stream (skip embedded cover art)
+			if hasVideo || stream.Disposition.AttachedPic == 1 {
+				continue
+			}
+			hasVideo = true
+			info.Width = stream.Width
+			info.Height = stream.Height
+			info.VideoCodec = stream.CodecName
+			info.VideoProfile = stream.Profile
+			info.PixelFormat = stream.PixFmt
+			info.FrameRate = parseFrameRate(stream.AvgFrameRate)
+			info.Rotation = streamRotation(stream)
+
+			// Some containers only report the duration on the stream
+			if info.Duration == 0 {
+				info.Duration = parseFloat(stream.Duration)
+			}
+		case "audio":
+			info.AudioTracks++
+			if info.AudioTracks == 1 {
+				info.AudioCodec = stream.CodecName
+				info.AudioChannels = stream.Channels
+				info.AudioSampleRate = int(parseFloat(stream.SampleRate))
+			}
+		}
+	}
+
+	// Reject files ffmpeg can open but that hold nothing we can transcode
+	if !hasVideo {
+		return nil, permanent(fmt.Errorf("uploaded file contains no video stream"))
+	}
+	if info.Width == 0 || info.Height == 0 {
+		return nil, permanent(fmt.Errorf("uploaded file has an invalid video resolution (%dx%d)", info.Width, info.Height))
+	}
+	if info.Duration <= 0 {
+		return nil, permanent(fmt.Errorf("uploaded file has no measurable duration"))
+	}
+
+	return info, nil
+}
+
+// Stores the media info for a video (replacing any previous probe) and fills in Video.Duration
+func SaveMediaInfo(db *gorm.DB, videoID uint, info *models.MediaInfo) error {
+	info.VideoID = videoID
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Upsert on video_id so a re-processed video keeps a single row
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "video_id"}},
+			UpdateAll: true,
+		}).Create(info).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Video{}).
+			Where("id = ?", videoID).
+			Update("duration", int(math.Round(info.Duration))).Error
+	})
+}
+
+// Display size of the video (width and height swapped for portrait recordings stored rotated)
+func displaySize(info *models.MediaInfo) (int, int) {
+	if info.Rotation == 90 || info.Rotation == 270 {
+		return info.Height, info.Width
+	}
+	return info.Width, info.Height
+}
+
+// Rotation of a stream in degrees, normalized to 0, 90, 180 or 270
+func streamRotation(stream ffprobeStream) int {
+	rotation := 0
+	if value, ok := stream.Tags["rotate"]; ok {
+		rotation, _ = strconv.Atoi(value)
+	}
+	for _, sideData := range stream.SideDataList {
+		if sideData.Rotation != 0 {
+			rotation = sideData.Rotation
+		}
+	}
+
+	rotation %= 360
+	if rotation < 0 {
+		rotation += 360
+	}
+	return rotation
+}
+
+// Parses an ffprobe rational ("30000/1001") into frames per second
+func parseFrameRate(value string) float64 {
+	num, den, found := strings.Cut(value, "/")
+	if !found {
+		return parseFloat(value)
+	}
+
+	d := parseFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return parseFloat(num) / d
+}
+
+func parseFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
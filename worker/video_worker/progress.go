@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alex6damian/GoSport/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Minimum delay between two progress writes (stage changes are always written)
+const progressWriteInterval = 2 * time.Second
+
+// Share of the overall progress taken by each stage (start, end) in percent
+var stageRanges = map[string][2]float64{
+	"download":   {0, 5},
+	"probe":      {5, 7},
+	"transcode":  {7, 85},
+	"thumbnails": {85, 90},
+	"upload":     {90, 100},
+}
+
+// Persists the stage, overall percentage and ETA of a job while it is processed
+type progressReporter struct {
+	db        *gorm.DB
+	jobID     uint
+	videoID   uint
+	startedAt time.Time
+
+	mu        sync.Mutex
+	stage     string
+	lastWrite time.Time
+}
+
+func newProgressReporter(db *gorm.DB, job *models.ProcessingJob) *progressReporter {
+	startedAt := time.Now()
+	if job.StartedAt != nil {
+		startedAt = *job.StartedAt
+	}
+	return &progressReporter{db: db, jobID: job.ID, videoID: job.VideoID, startedAt: startedAt}
+}
+
+// Enters a new stage (written immediately)
+func (p *progressReporter) Stage(stage string) {
+	p.mu.Lock()
+	p.stage = stage
+	p.mu.Unlock()
+
+	p.write(0, true)
+}
+
+// Reports the fraction (0-1) of the current stage that is done (throttled)
+func (p *progressReporter) Update(fraction float64) {
+	p.write(fraction, false)
+}
+
+func (p *progressReporter) write(fraction float64, force bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if !force && now.Sub(p.lastWrite) < progressWriteInterval {
+		return
+	}
+	p.lastWrite = now
+
+	fraction = math.Max(0, math.Min(1, fraction))
+	span := stageRanges[p.stage]
+	percent := span[0] + (span[1]-span[0])*fraction
+
+	updates := map[string]interface{}{
+		"stage":    p.stage,
+		"progress": math.Round(percent*10) / 10,
+	}
+
+	// Linear extrapolation of the time spent so far
+	if percent > 0 {
+		elapsed := now.Sub(p.startedAt).Seconds()
+		updates["eta_seconds"] = int(math.Ceil(elapsed * (100 - percent) / percent))
+	}
+
+	if err := p.db.Model(&models.ProcessingJob{}).Where("id = ?", p.jobID).Updates(updates).Error; err != nil {
+		log.Printf("⚠️  Failed to save progress of job ID %d: %v", p.jobID, err)
+		return
+	}
+	notifyProgress(p.db, p.videoID)
+}
+
+// Reads ffmpeg "-progress pipe:1" output and reports the processed media time (seconds)
+func parseFFmpegProgress(r io.Reader, onTime func(seconds float64)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || key != "out_time_us" {
+			continue
+		}
+
+		// N/A until the first frame is written
+		us, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+		onTime(float64(us) / 1e6)
+	}
+}
+
+// io.Writer wrapper reporting how many bytes went through it
+type countingWriter struct {
+	w       io.Writer
+	written int64
+	onWrite func(written int64)
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.written += int64(n)
+	c.onWrite(c.written)
+	return n, err
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	previewInterval     = 5   // Seconds between two scrub preview frames
+	previewTileWidth    = 160 // Size of one tile in the sprite sheet
+	previewTileHeight   = 90
+	spriteColumns       = 10 // Tiles per row
+	spriteRows          = 10 // Rows per sheet (a new sheet is started when full)
+	thumbnailCandidates = 4  // Number of candidate thumbnails offered to the creator
+	thumbnailWidth      = 1280
+)
+
+// Artwork generated for a video (paths are relative to the artwork output directory)
+type ThumbnailSet struct {
+	Poster     string
+	Candidates []string
+	PreviewVTT string
+	Sprites    []string
+}
+
+// Generates the poster frame, candidate thumbnails and the scrub preview sprite sheets + WebVTT index
+func generateThumbnails(ctx context.Context, inputPath, outputDir string, duration float64) (*ThumbnailSet, error) {
+	framesDir := filepath.Join(outputDir, "frames")
+	if err := os.MkdirAll(framesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frames directory: %v", err)
+	}
+	// Raw preview frames are only needed to build the sprites
+	defer os.RemoveAll(framesDir)
+
+	// Extract one small frame every previewInterval seconds
+	frames, err := extractPreviewFrames(ctx, inputPath, framesDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames could be extracted from video")
+	}
+
+	// Fall back to an approximate duration from the number of preview frames
+	if duration <= 0 {
+		duration = float64(len(frames) * previewInterval)
+	}
+	set := &ThumbnailSet{}
+
+	// Poster: most representative frame shortly after the start (skips black intros)
+	set.Poster = "poster.jpg"
+	if err := extractFrame(ctx, inputPath, filepath.Join(outputDir, set.Poster), duration*0.1, true); err != nil {
+		return nil, err
+	}
+
+	// Candidates: evenly spread over the video
+	candidatesDir := filepath.Join(outputDir, "candidates")
+	if err := os.MkdirAll(candidatesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create candidates directory: %v", err)
+	}
+	for i := 1; i <= thumbnailCandidates; i++ {
+		name := fmt.Sprintf("candidates/%d.jpg", i)
+		at := duration * float64(i) / float64(thumbnailCandidates+1)
+		if err := extractFrame(ctx, inputPath, filepath.Join(outputDir, name), at, false); err != nil {
+			// Seeking too close to the end can yield no frame, a missing candidate is not fatal
+			log.Printf("Skipping thumbnail candidate %d: %v", i, err)
+			continue
+		}
+		set.Candidates = append(set.Candidates, name)
+	}
+
+	// Sprite sheets + WebVTT index for scrub previews
+	sprites, err := buildSpriteSheets(frames, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	set.Sprites = sprites
+
+	set.PreviewVTT = "previews.vtt"
+	if err := writePreviewVTT(filepath.Join(outputDir, set.PreviewVTT), len(frames)); err != nil {
+		return nil, fmt.Errorf("failed to write preview VTT: %v", err)
+	}
+
+	return set, nil
+}
+
+// Extracts one scaled frame every previewInterval seconds, returns the sorted frame paths
+func extractPreviewFrames(ctx context.Context, inputPath, framesDir string) ([]string, error) {
+	filter := fmt.Sprintf(
+		"fps=1/%d,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2",
+		previewInterval, previewTileWidth, previewTileHeight, previewTileWidth, previewTileHeight,
+	)
+
+	cmd := exec.CommandContext(ctx,
+		"ffmpeg",
+		"-y",
+		"-i", inputPath, // Input file
+		"-vf", filter,
+		"-q:v", "5", // JPEG quality (2 = best, 31 = worst)
+		filepath.Join(framesDir, "frame_%05d.jpg"),
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg preview frame extraction failed: %v, output: %s", err, string(output))
+	}
+
+	frames, err := filepath.Glob(filepath.Join(framesDir, "frame_*.jpg"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list preview frames: %v", err)
+	}
+	sort.Strings(frames)
+
+	return frames, nil
+}
+
+// Extracts a single full-size frame at the given timestamp (seconds)
+func extractFrame(ctx context.Context, inputPath, outputPath string, at float64, representative bool) error {
+	filter := fmt.Sprintf("scale=%d:-2", thumbnailWidth)
+	if representative {
+		// Let ffmpeg pick the most representative frame of the next batch
+		filter = "thumbnail," + filter
+	}
+
+	cmd := exec.CommandContext(ctx,
+		"ffmpeg",
+		"-y",
+		"-ss", strconv.FormatFloat(at, 'f', 3, 64), // Seek before input (fast)
+		"-i", inputPath,
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", "3",
+		outputPath,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg frame extraction failed: %v, output: %s", err, string(output))
+	}
+
+	// ffmpeg exits cleanly when seeking past the end, but writes nothing
+	if _, err := os.Stat(outputPath); err != nil {
+		return fmt.Errorf("no frame found at %.3fs", at)
+	}
+
+	return nil
+}
+
+// Stitches the preview frames into one or more sprite sheets, returns their relative paths
+func buildSpriteSheets(frames []string, outputDir string) ([]string, error) {
+	spritesDir := filepath.Join(outputDir, "sprites")
+	if err := os.MkdirAll(spritesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sprites directory: %v", err)
+	}
+
+	perSheet := spriteColumns * spriteRows
+	var sprites []string
+
+	for start := 0; start < len(frames); start += perSheet {
+		end := start + perSheet
+		if end > len(frames) {
+			end = len(frames)
+		}
+		batch := frames[start:end]
+
+		// Only allocate the rows actually used by this sheet
+		rows := (len(batch) + spriteColumns - 1) / spriteColumns
+		columns := spriteColumns
+		if len(batch) < spriteColumns {
+			columns = len(batch)
+		}
+		sheet := image.NewRGBA(image.Rect(0, 0, columns*previewTileWidth, rows*previewTileHeight))
+
+		for i, framePath := range batch {
+			frame, err := decodeJPEG(framePath)
+			if err != nil {
+				return nil, err
+			}
+
+			x := (i % spriteColumns) * previewTileWidth
+			y := (i / spriteColumns) * previewTileHeight
+			draw.Draw(sheet, image.Rect(x, y, x+previewTileWidth, y+previewTileHeight), frame, frame.Bounds().Min, draw.Src)
+		}
+
+		name := spriteName(start / perSheet)
+		out, err := os.Create(filepath.Join(outputDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sprite sheet: %v", err)
+		}
+		err = jpeg.Encode(out, sheet, &jpeg.Options{Quality: 75})
+		out.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode sprite sheet: %v", err)
+		}
+
+		sprites = append(sprites, name)
+	}
+
+	return sprites, nil
+}
+
+// Writes the WebVTT file mapping every preview interval to its tile in the sprite sheets
+func writePreviewVTT(path string, frameCount int) error {
+	perSheet := spriteColumns * spriteRows
+
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	for i := 0; i < frameCount; i++ {
+		start := i * previewInterval
+		end := start + previewInterval
+
+		index := i % perSheet
+		x := (index % spriteColumns) * previewTileWidth
+		y := (index / spriteColumns) * previewTileHeight
+
+		// Sprite URIs are relative to the VTT file (the API presigns them when serving it)
+		fmt.Fprintf(&b, "%s --> %s\n", formatVTTTimestamp(start), formatVTTTimestamp(end))
+		fmt.Fprintf(&b, "%s#xywh=%d,%d,%d,%d\n\n", spriteName(i/perSheet), x, y, previewTileWidth, previewTileHeight)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// Relative path of the n-th sprite sheet
+func spriteName(n int) string {
+	return fmt.Sprintf("sprites/sprite_%03d.jpg", n)
+}
+
+// Formats seconds as a WebVTT timestamp (HH:MM:SS.mmm)
+func formatVTTTimestamp(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d.000", seconds/3600, (seconds%3600)/60, seconds%60)
+}
+
+func decodeJPEG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame %s: %v", path, err)
+	}
+	defer f.Close()
+
+	img, err := jpeg.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame %s: %v", path, err)
+	}
+
+	return img, nil
+}
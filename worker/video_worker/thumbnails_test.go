@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePreviewVTT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "previews.vtt")
+
+	// 102 frames: a full first sheet (10x10 tiles) and 2 tiles on the second one
+	if err := writePreviewVTT(path, 102); err != nil {
+		t.Fatalf("writePreviewVTT() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(data)
+	if !strings.HasPrefix(content, "WEBVTT\n\n") {
+		t.Fatalf("missing WEBVTT header: %q", content[:min(len(content), 20)])
+	}
+
+	// Each cue is "timing\nsprite#xywh=...", separated by blank lines
+	cues := strings.Split(strings.TrimSpace(strings.TrimPrefix(content, "WEBVTT\n\n")), "\n\n")
+	if len(cues) != 102 {
+		t.Fatalf("got %d cues, want 102", len(cues))
+	}
+
+	tests := []struct {
+		index  int
+		timing string
+		tile   string
+	}{
+		{0, "00:00:00.000 --> 00:00:05.000", "sprites/sprite_000.jpg#xywh=0,0,160,90"},
+		{1, "00:00:05.000 --> 00:00:10.000", "sprites/sprite_000.jpg#xywh=160,0,160,90"},
+		{9, "00:00:45.000 --> 00:00:50.000", "sprites/sprite_000.jpg#xywh=1440,0,160,90"},
+		{10, "00:00:50.000 --> 00:00:55.000", "sprites/sprite_000.jpg#xywh=0,90,160,90"},
+		{11, "00:00:55.000 --> 00:01:00.000", "sprites/sprite_000.jpg#xywh=160,90,160,90"},
+		{99, "00:08:15.000 --> 00:08:20.000", "sprites/sprite_000.jpg#xywh=1440,810,160,90"},
+		{100, "00:08:20.000 --> 00:08:25.000", "sprites/sprite_001.jpg#xywh=0,0,160,90"},
+		{101, "00:08:25.000 --> 00:08:30.000", "sprites/sprite_001.jpg#xywh=160,0,160,90"},
+	}
+
+	for _, tt := range tests {
+		lines := strings.Split(cues[tt.index], "\n")
+		if len(lines) != 2 {
+			t.Errorf("cue %d = %q, want 2 lines", tt.index, cues[tt.index])
+			continue
+		}
+		if lines[0] != tt.timing {
+			t.Errorf("cue %d timing = %q, want %q", tt.index, lines[0], tt.timing)
+		}
+		if lines[1] != tt.tile {
+			t.Errorf("cue %d tile = %q, want %q", tt.index, lines[1], tt.tile)
+		}
+	}
+
+	// Cues are contiguous, each one starts where the previous one ended
+	for i := 1; i < len(cues); i++ {
+		previousEnd := strings.Split(strings.SplitN(cues[i-1], "\n", 2)[0], " --> ")[1]
+		start := strings.Split(cues[i], " --> ")[0]
+		if start != previousEnd {
+			t.Errorf("cue %d starts at %s, previous cue ends at %s", i, start, previousEnd)
+		}
+	}
+}
+
+func TestFormatVTTTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "00:00:00.000"},
+		{59, "00:00:59.000"},
+		{60, "00:01:00.000"},
+		{3599, "00:59:59.000"},
+		{3600, "01:00:00.000"},
+		{36061, "10:01:01.000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatVTTTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("formatVTTTimestamp(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}